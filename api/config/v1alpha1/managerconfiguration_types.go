@@ -0,0 +1,223 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManagerConfiguration is the Schema for the manager configuration file.
+//
+// Logging.Level is reloaded when the file changes. Changes to any other field
+// require a restart of the manager.
+type ManagerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerName is the name of the controller that manages Gateways of this class.
+	// It is a shorthand for a single controller in Controllers, and may not be set
+	// together with more than one controller.
+	//
+	// If unset, defaults to "sample.io/gateway-manager" when Controllers is unset.
+	ControllerName string `json:"controllerName,omitempty"`
+
+	// Controllers are the controllers served by the manager. Each controller manages the
+	// GatewayClasses of its name and accepts the oldest of them.
+	//
+	// If unset, defaults to a single controller named ControllerName.
+	Controllers []ControllerConfiguration `json:"controllers,omitempty"`
+
+	// WatchNamespaces restricts the namespaces of the watched namespaced resources.
+	//
+	// If unset, all namespaces are watched.
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// LeaderElection configures leader election of the manager.
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+
+	// Metrics configures the metrics endpoint.
+	Metrics MetricsConfiguration `json:"metrics,omitempty"`
+
+	// Health configures the health probe endpoints.
+	Health HealthConfiguration `json:"health,omitempty"`
+
+	// Admin configures the admin server that serves the debug endpoints.
+	Admin AdminConfiguration `json:"admin,omitempty"`
+
+	// XDS configures the connection of the proxies to the xDS server.
+	XDS XDSConfiguration `json:"xds,omitempty"`
+
+	// Proxy configures the proxies provisioned for Gateways.
+	Proxy ProxyConfiguration `json:"proxy,omitempty"`
+
+	// Logging configures the manager logger.
+	Logging LoggingConfiguration `json:"logging,omitempty"`
+
+	// FeatureGates enables or disables named features of the manager.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// ControllerConfiguration configures a controller served by the manager.
+type ControllerConfiguration struct {
+	// Name is the controllerName of the GatewayClasses managed by the controller.
+	Name string `json:"name"`
+
+	// DefaultGatewayClassConfig is the GatewayClassConfig of the managed GatewayClasses
+	// without a parametersRef.
+	//
+	// If unset, GatewayClasses without a parametersRef have no GatewayClassConfig.
+	DefaultGatewayClassConfig *GatewayClassConfigReference `json:"defaultGatewayClassConfig,omitempty"`
+}
+
+// GatewayClassConfigReference references a GatewayClassConfig.
+type GatewayClassConfigReference struct {
+	// Namespace is the namespace of the GatewayClassConfig.
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the GatewayClassConfig.
+	Name string `json:"name"`
+}
+
+// LeaderElectionConfiguration configures leader election of the manager.
+type LeaderElectionConfiguration struct {
+	// LeaderElect enables leader election, ensuring there is only one active manager.
+	//
+	// If unset, defaults to false.
+	LeaderElect *bool `json:"leaderElect,omitempty"`
+
+	// ResourceName is the name of the Lease used for leader election.
+	//
+	// If unset, defaults to "96ab2193.solo.io".
+	ResourceName string `json:"resourceName,omitempty"`
+
+	// ResourceNamespace is the namespace of the Lease used for leader election.
+	//
+	// If unset, defaults to the namespace the manager runs in.
+	ResourceNamespace string `json:"resourceNamespace,omitempty"`
+
+	// LeaseDuration is the duration non-leader candidates wait to force acquire leadership.
+	//
+	// If unset, defaults to 15s.
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// RenewDeadline is the duration the leader retries refreshing leadership before giving up.
+	//
+	// If unset, defaults to 10s.
+	RenewDeadline *metav1.Duration `json:"renewDeadline,omitempty"`
+
+	// RetryPeriod is the duration clients wait between leader election attempts.
+	//
+	// If unset, defaults to 2s.
+	RetryPeriod *metav1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// MetricsConfiguration configures the metrics endpoint.
+type MetricsConfiguration struct {
+	// BindAddress is the address the metrics endpoint binds to. Set to "0" to disable
+	// the metrics endpoint.
+	//
+	// If unset, defaults to ":8080".
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// HealthConfiguration configures the health probe endpoints.
+type HealthConfiguration struct {
+	// BindAddress is the address the health probe endpoints bind to.
+	//
+	// If unset, defaults to ":8081".
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// AdminConfiguration configures the admin server.
+type AdminConfiguration struct {
+	// BindAddress is the address the admin server binds to. Requests are authenticated
+	// with a bearer token and authorized for the request path unless the address is a
+	// loopback address. Set to "0" to disable the admin server.
+	//
+	// If unset, defaults to "127.0.0.1:9090".
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// XDSConfiguration configures the connection of the proxies to the xDS server.
+type XDSConfiguration struct {
+	// Address is the host:port the proxies connect to the xDS server at, written to
+	// their bootstrap configuration. The manager doesn't serve xDS itself.
+	//
+	// If unset, the proxies aren't bootstrapped: no bootstrap ConfigMap or xDS
+	// certificate is provisioned, and the proxies run the default configuration of
+	// their image.
+	Address string `json:"address,omitempty"`
+
+	// CASecret is the Secret the CA of the xDS connections is stored in. The manager
+	// creates the CA if the Secret doesn't exist.
+	//
+	// If unset, defaults to the "sample-gateway-controller-xds-ca" Secret in the
+	// "sample-gateway-controller-system" namespace.
+	CASecret SecretReference `json:"caSecret,omitempty"`
+
+	// ServerSecret is the Secret the manager stores the certificate of the xDS server
+	// in, issued by the CA to the host of Address, for the xDS server to mount. It is
+	// only issued if Address is set.
+	//
+	// If unset, defaults to the "sample-gateway-controller-xds-server" Secret in the
+	// namespace of CASecret.
+	ServerSecret SecretReference `json:"serverSecret,omitempty"`
+
+	// CertificateValidity is the validity of the certificates issued to the proxies and
+	// the xDS server.
+	// They are renewed once two thirds of their validity elapsed.
+	//
+	// If unset, defaults to 24h.
+	CertificateValidity *metav1.Duration `json:"certificateValidity,omitempty"`
+}
+
+// SecretReference references a Secret.
+type SecretReference struct {
+	// Namespace is the namespace of the Secret.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the Secret.
+	Name string `json:"name,omitempty"`
+}
+
+// ProxyConfiguration configures the proxies provisioned for Gateways.
+type ProxyConfiguration struct {
+	// Image is the container image of the proxies whose GatewayClassConfig doesn't
+	// set one.
+	//
+	// If unset, defaults to "docker.io/envoyproxy/envoy:v1.28.0".
+	Image string `json:"image,omitempty"`
+}
+
+// LoggingConfiguration configures the manager logger.
+type LoggingConfiguration struct {
+	// Level is the log level, one of "debug", "info", "error", or an integer
+	// verbosity greater than 0.
+	//
+	// If unset, defaults to "info".
+	Level string `json:"level,omitempty"`
+
+	// Format is the encoding of the log entries, "json" or "console".
+	//
+	// If unset, defaults to "json".
+	Format string `json:"format,omitempty"`
+
+	// Development enables development mode logging with stack traces on warnings
+	// and no sampling.
+	//
+	// If unset, defaults to false.
+	Development *bool `json:"development,omitempty"`
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+// annotationHubSpec preserves the fields of the v1beta1 spec that v1alpha1 can't
+// represent on the v1alpha1 object, as the JSON of a hubOnlySpec.
+const annotationHubSpec = "sample.io/v1beta1-spec"
+
+// hubOnlySpec holds the fields of the v1beta1 spec that v1alpha1 can't represent.
+type hubOnlySpec struct {
+	MergeGateways       *v1beta1.MergeGatewaysScope       `json:"mergeGateways,omitempty"`
+	Autoscaling         *v1beta1.ProxyAutoscaling         `json:"autoscaling,omitempty"`
+	PodDisruptionBudget *v1beta1.ProxyPodDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+	DrainTimeout        *metav1.Duration                  `json:"drainTimeout,omitempty"`
+}
+
+// ConvertTo converts this GatewayClassConfig to the hub version.
+func (src *GatewayClassConfig) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.GatewayClassConfig)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", dstRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.GatewayClassConfigSpec{Foo: src.Spec.Foo}
+	if proxy := src.Spec.Proxy; proxy != nil {
+		if proxy.Replicas != nil || proxy.Image != "" || proxy.Resources != nil {
+			dst.Spec.Deployment = &v1beta1.ProxyDeployment{Replicas: proxy.Replicas}
+		}
+		if proxy.Image != "" || proxy.Resources != nil {
+			dst.Spec.Deployment.Container = &v1beta1.ProxyContainer{
+				Image:     proxy.Image,
+				Resources: proxy.Resources,
+			}
+		}
+		if svc := proxy.Service; svc != nil {
+			dst.Spec.Service = &v1beta1.ProxyService{
+				Type:                  svc.Type,
+				Annotations:           svc.Annotations,
+				LoadBalancerClass:     svc.LoadBalancerClass,
+				ExternalTrafficPolicy: svc.ExternalTrafficPolicy,
+			}
+		}
+	}
+	if data, ok := src.Annotations[annotationHubSpec]; ok {
+		var hubOnly hubOnlySpec
+		if err := json.Unmarshal([]byte(data), &hubOnly); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", annotationHubSpec, err)
+		}
+		dst.Spec.MergeGateways = hubOnly.MergeGateways
+		if hubOnly.Autoscaling != nil || hubOnly.PodDisruptionBudget != nil || hubOnly.DrainTimeout != nil {
+			if dst.Spec.Deployment == nil {
+				dst.Spec.Deployment = &v1beta1.ProxyDeployment{}
+			}
+			dst.Spec.Deployment.Autoscaling = hubOnly.Autoscaling
+			dst.Spec.Deployment.PodDisruptionBudget = hubOnly.PodDisruptionBudget
+			dst.Spec.Deployment.DrainTimeout = hubOnly.DrainTimeout
+		}
+		dst.Annotations = copyAnnotations(src.Annotations)
+		delete(dst.Annotations, annotationHubSpec)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+	dst.Status = v1beta1.GatewayClassConfigStatus{
+		ObservedFoo:       src.Status.ObservedFoo,
+		SupportedFeatures: src.Status.SupportedFeatures,
+		Conditions:        src.Status.Conditions,
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the hub version to this GatewayClassConfig.
+func (dst *GatewayClassConfig) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.GatewayClassConfig)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", srcRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = GatewayClassConfigSpec{Foo: src.Spec.Foo}
+	hubOnly := hubOnlySpec{MergeGateways: src.Spec.MergeGateways}
+	if deploy := src.Spec.Deployment; deploy != nil {
+		hubOnly.Autoscaling = deploy.Autoscaling
+		hubOnly.PodDisruptionBudget = deploy.PodDisruptionBudget
+		hubOnly.DrainTimeout = deploy.DrainTimeout
+	}
+	if hubOnly != (hubOnlySpec{}) {
+		data, err := json.Marshal(hubOnly)
+		if err != nil {
+			return err
+		}
+		dst.Annotations = copyAnnotations(src.Annotations)
+		dst.Annotations[annotationHubSpec] = string(data)
+	}
+	if src.Spec.Deployment != nil || src.Spec.Service != nil {
+		dst.Spec.Proxy = &ProxyConfig{}
+	}
+	if deploy := src.Spec.Deployment; deploy != nil {
+		dst.Spec.Proxy.Replicas = deploy.Replicas
+		if c := deploy.Container; c != nil {
+			dst.Spec.Proxy.Image = c.Image
+			dst.Spec.Proxy.Resources = c.Resources
+		}
+	}
+	if svc := src.Spec.Service; svc != nil {
+		dst.Spec.Proxy.Service = &ProxyServiceConfig{
+			Type:                  svc.Type,
+			Annotations:           svc.Annotations,
+			LoadBalancerClass:     svc.LoadBalancerClass,
+			ExternalTrafficPolicy: svc.ExternalTrafficPolicy,
+		}
+	}
+	dst.Status = GatewayClassConfigStatus{
+		ObservedFoo:       src.Status.ObservedFoo,
+		SupportedFeatures: src.Status.SupportedFeatures,
+		Conditions:        src.Status.Conditions,
+	}
+
+	return nil
+}
+
+// copyAnnotations returns a copy of annotations, which is never nil.
+func copyAnnotations(annotations map[string]string) map[string]string {
+	res := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		res[k] = v
+	}
+	return res
+}
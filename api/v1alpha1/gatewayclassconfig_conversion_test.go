@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"math/rand"
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+const fuzzIterations = 1000
+
+// fuzzerFuncs keep the fuzzed objects within what each version can represent: the
+// versions don't distinguish an empty proxy configuration from an unset one.
+func fuzzerFuncs(_ serializer.CodecFactory) []interface{} {
+	return []interface{}{
+		func(q *resource.Quantity, c fuzz.Continue) {
+			*q = *resource.NewQuantity(c.Int63n(1000), resource.DecimalSI)
+		},
+		func(v *intstr.IntOrString, c fuzz.Continue) {
+			if c.RandBool() {
+				*v = intstr.FromInt32(c.Int31())
+			} else {
+				*v = intstr.FromString(c.RandString())
+			}
+		},
+		func(spec *GatewayClassConfigSpec, c fuzz.Continue) {
+			c.FuzzNoCustom(spec)
+			if spec.Proxy != nil && *spec.Proxy == (ProxyConfig{}) {
+				spec.Proxy = nil
+			}
+		},
+		func(spec *v1beta1.GatewayClassConfigSpec, c fuzz.Continue) {
+			c.FuzzNoCustom(spec)
+			if d := spec.Deployment; d != nil {
+				if d.Container != nil && *d.Container == (v1beta1.ProxyContainer{}) {
+					d.Container = nil
+				}
+				if *d == (v1beta1.ProxyDeployment{}) {
+					spec.Deployment = nil
+				}
+			}
+		},
+	}
+}
+
+func newFuzzer(t *testing.T) *fuzz.Fuzzer {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fuzzer.FuzzerFor(
+		fuzzer.MergeFuzzerFuncs(metafuzzer.Funcs, fuzzerFuncs),
+		rand.NewSource(rand.Int63()),
+		serializer.NewCodecFactory(scheme),
+	)
+}
+
+func TestSpokeHubSpokeRoundTrip(t *testing.T) {
+	f := newFuzzer(t)
+	for i := 0; i < fuzzIterations; i++ {
+		src := &GatewayClassConfig{}
+		f.Fuzz(src)
+		src.TypeMeta = metav1.TypeMeta{}
+
+		hub := &v1beta1.GatewayClassConfig{}
+		if err := src.ConvertTo(hub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dst := &GatewayClassConfig{}
+		if err := dst.ConvertFrom(hub); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(src, dst) {
+			t.Fatalf("round trip changed the object: %s", diff.ObjectReflectDiff(src, dst))
+		}
+	}
+}
+
+func TestHubSpokeHubRoundTrip(t *testing.T) {
+	f := newFuzzer(t)
+	for i := 0; i < fuzzIterations; i++ {
+		src := &v1beta1.GatewayClassConfig{}
+		f.Fuzz(src)
+		src.TypeMeta = metav1.TypeMeta{}
+
+		spoke := &GatewayClassConfig{}
+		if err := spoke.ConvertFrom(src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dst := &v1beta1.GatewayClassConfig{}
+		if err := spoke.ConvertTo(dst); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(src, dst) {
+			t.Fatalf("round trip changed the object: %s", diff.ObjectReflectDiff(src, dst))
+		}
+	}
+}
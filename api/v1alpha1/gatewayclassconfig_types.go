@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -41,6 +42,65 @@ type GatewayClassConfigSpec struct {
 	//
 	// +kubebuilder:default="bar"
 	Foo string `json:"foo"`
+
+	// Proxy configures the proxies provisioned for the Gateways of the class.
+	//
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+}
+
+// ProxyConfig configures the proxies provisioned for Gateways.
+type ProxyConfig struct {
+	// Image is the container image of the proxy, e.g. "docker.io/envoyproxy/envoy:v1.28.0".
+	//
+	// If unset, the default proxy image of the manager is used.
+	//
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of proxy replicas of each Gateway.
+	//
+	// If unset, defaults to 1.
+	//
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources are the compute resources of the proxy container.
+	//
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Service configures the Service exposing the proxies of each Gateway.
+	//
+	// +optional
+	Service *ProxyServiceConfig `json:"service,omitempty"`
+}
+
+// ProxyServiceConfig configures the Service exposing the proxies of a Gateway.
+type ProxyServiceConfig struct {
+	// Type is the type of the Service, one of ClusterIP, NodePort or LoadBalancer.
+	//
+	// If unset, defaults to LoadBalancer.
+	//
+	// +optional
+	Type *corev1.ServiceType `json:"type,omitempty"`
+
+	// Annotations are added to the Service, e.g. to configure the load balancer.
+	//
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// LoadBalancerClass is the class of the load balancer implementation of a
+	// LoadBalancer Service.
+	//
+	// +optional
+	LoadBalancerClass *string `json:"loadBalancerClass,omitempty"`
+
+	// ExternalTrafficPolicy is the external traffic policy of a NodePort or
+	// LoadBalancer Service, Cluster or Local.
+	//
+	// +optional
+	ExternalTrafficPolicy *corev1.ServiceExternalTrafficPolicy `json:"externalTrafficPolicy,omitempty"`
 }
 
 // GatewayClassConfigStatus defines the observed state of GatewayClassConfig.
@@ -50,6 +110,12 @@ type GatewayClassConfigStatus struct {
 	//
 	ObservedFoo string `json:"observedFoo"`
 
+	// SupportedFeatures mirrors the Gateway API features supported by the accepted
+	// GatewayClass that references this GatewayClassConfig.
+	//
+	// +optional
+	SupportedFeatures []string `json:"supportedFeatures,omitempty"`
+
 	// Conditions represent the observation state of the GatewayClassConfig.
 	//
 	// +patchMergeKey=type
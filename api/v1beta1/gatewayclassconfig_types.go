@@ -0,0 +1,233 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:resource:categories=gateway-api,shortName=gcc
+
+// GatewayClassConfig is the Schema for the gatewayclassconfigs API.
+type GatewayClassConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GatewayClassConfigSpec   `json:"spec,omitempty"`
+	Status GatewayClassConfigStatus `json:"status,omitempty"`
+}
+
+// GatewayClassConfigSpec defines the desired state of GatewayClassConfig.
+type GatewayClassConfigSpec struct {
+	// Foo is an example field that represents Gateway configuration.
+	//
+	// If unset, defaults to "bar".
+	//
+	// +kubebuilder:default="bar"
+	Foo string `json:"foo"`
+
+	// Deployment configures the proxy Deployment provisioned for each Gateway of the class.
+	//
+	// +optional
+	Deployment *ProxyDeployment `json:"deployment,omitempty"`
+
+	// Service configures the Service exposing the proxies of each Gateway of the class.
+	//
+	// +optional
+	Service *ProxyService `json:"service,omitempty"`
+
+	// MergeGateways merges the Gateways of the class onto a proxy Deployment and Service
+	// shared by the Gateways of the class, or of the class in each namespace, instead of
+	// provisioning a proxy for each Gateway. The listener ports of merged Gateways must
+	// not collide.
+	//
+	// If unset, each Gateway has its own proxy.
+	//
+	// +optional
+	MergeGateways *MergeGatewaysScope `json:"mergeGateways,omitempty"`
+}
+
+// MergeGatewaysScope is the scope of the Gateways merged onto a shared proxy.
+//
+// +kubebuilder:validation:Enum=GatewayClass;Namespace
+type MergeGatewaysScope string
+
+const (
+	// MergeGatewaysGatewayClass merges all the Gateways of the class onto a proxy in the
+	// namespace of the GatewayClassConfig.
+	MergeGatewaysGatewayClass MergeGatewaysScope = "GatewayClass"
+	// MergeGatewaysNamespace merges the Gateways of the class in each namespace onto a
+	// proxy in that namespace.
+	MergeGatewaysNamespace MergeGatewaysScope = "Namespace"
+)
+
+// ProxyDeployment configures the proxy Deployment of a Gateway.
+type ProxyDeployment struct {
+	// Replicas is the number of proxy replicas.
+	//
+	// If unset, defaults to 1.
+	//
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Container configures the proxy container.
+	//
+	// +optional
+	Container *ProxyContainer `json:"container,omitempty"`
+
+	// Autoscaling configures a HorizontalPodAutoscaler scaling the proxy Deployment,
+	// which then ignores Replicas.
+	//
+	// +optional
+	Autoscaling *ProxyAutoscaling `json:"autoscaling,omitempty"`
+
+	// PodDisruptionBudget configures a PodDisruptionBudget of the proxy pods.
+	//
+	// +optional
+	PodDisruptionBudget *ProxyPodDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+
+	// DrainTimeout is how long a terminating proxy drains its listeners, and how long the
+	// proxies of a deleted Gateway drain before its infrastructure is deleted. A zero
+	// timeout disables draining.
+	//
+	// If unset, defaults to 30s.
+	//
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+}
+
+// ProxyAutoscaling configures the HorizontalPodAutoscaler of a proxy Deployment.
+type ProxyAutoscaling struct {
+	// MinReplicas is the lower limit of the number of proxy replicas.
+	//
+	// If unset, defaults to 1.
+	//
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit of the number of proxy replicas.
+	//
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// Metrics are the metrics used to compute the number of proxy replicas, e.g. the
+	// CPU or memory utilization of the proxies or a custom metric.
+	//
+	// If unset, defaults to an average CPU utilization of 80%.
+	//
+	// +optional
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// ProxyPodDisruptionBudget configures the PodDisruptionBudget of proxy pods. Exactly one
+// of MinAvailable and MaxUnavailable must be set.
+type ProxyPodDisruptionBudget struct {
+	// MinAvailable is the number or percentage of proxy pods that must remain available
+	// during a voluntary disruption.
+	//
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of proxy pods that may be unavailable
+	// during a voluntary disruption.
+	//
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ProxyContainer configures the proxy container.
+type ProxyContainer struct {
+	// Image is the container image of the proxy, e.g. "docker.io/envoyproxy/envoy:v1.28.0".
+	//
+	// If unset, the default proxy image of the manager is used.
+	//
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Resources are the compute resources of the proxy container.
+	//
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ProxyService configures the Service exposing the proxies of a Gateway.
+type ProxyService struct {
+	// Type is the type of the Service, one of ClusterIP, NodePort or LoadBalancer.
+	//
+	// If unset, defaults to LoadBalancer.
+	//
+	// +optional
+	Type *corev1.ServiceType `json:"type,omitempty"`
+
+	// Annotations are added to the Service, e.g. to configure the load balancer.
+	//
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// LoadBalancerClass is the class of the load balancer implementation of a
+	// LoadBalancer Service.
+	//
+	// +optional
+	LoadBalancerClass *string `json:"loadBalancerClass,omitempty"`
+
+	// ExternalTrafficPolicy is the external traffic policy of a NodePort or
+	// LoadBalancer Service, Cluster or Local.
+	//
+	// +optional
+	ExternalTrafficPolicy *corev1.ServiceExternalTrafficPolicy `json:"externalTrafficPolicy,omitempty"`
+}
+
+// GatewayClassConfigStatus defines the observed state of GatewayClassConfig.
+type GatewayClassConfigStatus struct {
+	// ObservedFoo is an example status field that is set when the GatewayClassConfig
+	// is reconciled.
+	//
+	ObservedFoo string `json:"observedFoo"`
+
+	// SupportedFeatures mirrors the Gateway API features supported by the accepted
+	// GatewayClass that references this GatewayClassConfig.
+	//
+	// +optional
+	SupportedFeatures []string `json:"supportedFeatures,omitempty"`
+
+	// Conditions represent the observation state of the GatewayClassConfig.
+	//
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GatewayClassConfigList contains a list of GatewayClassConfig
+type GatewayClassConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GatewayClassConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GatewayClassConfig{}, &GatewayClassConfigList{})
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/mutate-sample-io-v1beta1-gatewayclassconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=sample.io,resources=gatewayclassconfigs,verbs=create;update,versions=v1beta1,name=mgatewayclassconfig.sample.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-sample-io-v1beta1-gatewayclassconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=sample.io,resources=gatewayclassconfigs,verbs=create;update,versions=v1beta1,name=vgatewayclassconfig.sample.io,admissionReviewVersions=v1
+
+const (
+	// DefaultProxyReplicas is the default number of proxy replicas of each Gateway.
+	DefaultProxyReplicas int32 = 1
+	// DefaultProxyServiceType is the default type of the Service exposing the proxies.
+	DefaultProxyServiceType = corev1.ServiceTypeLoadBalancer
+	// DefaultProxyMinReplicas is the default lower limit of the autoscaled proxy replicas.
+	DefaultProxyMinReplicas int32 = 1
+	// DefaultProxyCPUUtilization is the default target average CPU utilization of the
+	// autoscaled proxies, in percent.
+	DefaultProxyCPUUtilization int32 = 80
+	// DefaultProxyDrainTimeout is the default drain timeout of the proxies.
+	DefaultProxyDrainTimeout = 30 * time.Second
+	// maxProxyDrainTimeout is the maximum drain timeout of the proxies.
+	maxProxyDrainTimeout = time.Hour
+
+	// maxInUseGateways is the maximum number of live Gateways listed in an error.
+	maxInUseGateways = 3
+)
+
+// imageRegex matches a container image reference, i.e. an optional registry, a
+// repository path, an optional tag and an optional digest.
+var imageRegex = regexp.MustCompile(`^` +
+	`(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[\w][\w.-]{0,127})?` +
+	`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+
+// supportedServiceTypes are the Service types of the proxies.
+var supportedServiceTypes = []string{
+	string(corev1.ServiceTypeClusterIP), string(corev1.ServiceTypeNodePort), string(corev1.ServiceTypeLoadBalancer),
+}
+
+// GatewayClassConfigWebhook defaults and validates GatewayClassConfigs.
+//
+// +kubebuilder:object:generate=false
+type GatewayClassConfigWebhook struct {
+	// GatewaysInUse returns the live Gateways of the GatewayClasses configured by the
+	// named GatewayClassConfig. Edits that are unsafe while Gateways are live are
+	// rejected if it returns any. If nil, no GatewayClassConfig is in use.
+	GatewaysInUse func(ctx context.Context, nsName types.NamespacedName) ([]types.NamespacedName, error)
+}
+
+// SetupWebhookWithManager registers the webhook with the webhook server of mgr.
+func (w *GatewayClassConfigWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&GatewayClassConfig{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default implements admission.CustomDefaulter.
+func (w *GatewayClassConfigWebhook) Default(_ context.Context, obj runtime.Object) error {
+	gcc, ok := obj.(*GatewayClassConfig)
+	if !ok {
+		return fmt.Errorf("expected a GatewayClassConfig, found %T", obj)
+	}
+	SetDefaults(gcc)
+	return nil
+}
+
+// SetDefaults sets the default value of the unset fields of gcc.
+func SetDefaults(gcc *GatewayClassConfig) {
+	SetSpecDefaults(&gcc.Spec)
+}
+
+// SetSpecDefaults sets the default value of the unset fields of spec.
+func SetSpecDefaults(spec *GatewayClassConfigSpec) {
+	if spec.Foo == "" {
+		spec.Foo = "bar"
+	}
+	if spec.Deployment == nil {
+		spec.Deployment = &ProxyDeployment{}
+	}
+	if spec.Deployment.Replicas == nil {
+		replicas := DefaultProxyReplicas
+		spec.Deployment.Replicas = &replicas
+	}
+	if spec.Deployment.DrainTimeout == nil {
+		spec.Deployment.DrainTimeout = &metav1.Duration{Duration: DefaultProxyDrainTimeout}
+	}
+	if as := spec.Deployment.Autoscaling; as != nil {
+		if as.MinReplicas == nil {
+			minReplicas := DefaultProxyMinReplicas
+			as.MinReplicas = &minReplicas
+		}
+		if len(as.Metrics) == 0 {
+			utilization := DefaultProxyCPUUtilization
+			as.Metrics = []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: &utilization,
+					},
+				},
+			}}
+		}
+	}
+	if spec.Service == nil {
+		spec.Service = &ProxyService{}
+	}
+	if spec.Service.Type == nil {
+		serviceType := DefaultProxyServiceType
+		spec.Service.Type = &serviceType
+	}
+}
+
+// ValidateCreate implements admission.CustomValidator.
+func (w *GatewayClassConfigWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	gcc, ok := obj.(*GatewayClassConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a GatewayClassConfig, found %T", obj)
+	}
+	return nil, invalid(gcc, ValidateSpec(&gcc.Spec, field.NewPath("spec")))
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (w *GatewayClassConfigWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldGCC, ok := oldObj.(*GatewayClassConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a GatewayClassConfig, found %T", oldObj)
+	}
+	gcc, ok := newObj.(*GatewayClassConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a GatewayClassConfig, found %T", newObj)
+	}
+
+	errs := ValidateSpec(&gcc.Spec, field.NewPath("spec"))
+	unsafe := unsafeUpdates(&oldGCC.Spec, &gcc.Spec, field.NewPath("spec"))
+	if len(errs) == 0 && len(unsafe) > 0 && w.GatewaysInUse != nil {
+		gateways, err := w.GatewaysInUse(ctx, types.NamespacedName{Namespace: gcc.Namespace, Name: gcc.Name})
+		if err != nil {
+			return nil, apierrors.NewInternalError(err)
+		}
+		if len(gateways) > 0 {
+			for _, path := range unsafe {
+				errs = append(errs, field.Forbidden(path,
+					fmt.Sprintf("may not be changed while Gateways are live: %s", gatewayNames(gateways))))
+			}
+		}
+	}
+
+	return nil, invalid(gcc, errs)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (w *GatewayClassConfigWebhook) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateSpec returns the errors of spec.
+func ValidateSpec(spec *GatewayClassConfigSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.Deployment != nil {
+		errs = append(errs, validateDeployment(spec.Deployment, path.Child("deployment"))...)
+	}
+	if spec.Service != nil {
+		errs = append(errs, validateService(spec.Service, path.Child("service"))...)
+	}
+	if scope := spec.MergeGateways; scope != nil && *scope != MergeGatewaysGatewayClass && *scope != MergeGatewaysNamespace {
+		errs = append(errs, field.NotSupported(path.Child("mergeGateways"), *scope, []string{
+			string(MergeGatewaysGatewayClass), string(MergeGatewaysNamespace),
+		}))
+	}
+
+	return errs
+}
+
+func validateDeployment(deploy *ProxyDeployment, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if deploy.Replicas != nil && *deploy.Replicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("replicas"), *deploy.Replicas, "must be greater than or equal to 0"))
+	}
+	if c := deploy.Container; c != nil {
+		if c.Image != "" && !imageRegex.MatchString(c.Image) {
+			errs = append(errs, field.Invalid(path.Child("container", "image"), c.Image,
+				"must be a container image reference, e.g. docker.io/envoyproxy/envoy:v1.28.0"))
+		}
+		if c.Resources != nil {
+			errs = append(errs, validateResources(c.Resources, path.Child("container", "resources"))...)
+		}
+	}
+	if as := deploy.Autoscaling; as != nil {
+		asPath := path.Child("autoscaling")
+		if as.MaxReplicas < 1 {
+			errs = append(errs, field.Invalid(asPath.Child("maxReplicas"), as.MaxReplicas, "must be greater than or equal to 1"))
+		}
+		if as.MinReplicas != nil {
+			switch {
+			case *as.MinReplicas < 1:
+				errs = append(errs, field.Invalid(asPath.Child("minReplicas"), *as.MinReplicas,
+					"must be greater than or equal to 1"))
+			case *as.MinReplicas > as.MaxReplicas:
+				errs = append(errs, field.Invalid(asPath.Child("minReplicas"), *as.MinReplicas,
+					"must be less than or equal to maxReplicas"))
+			}
+		}
+	}
+	if pdb := deploy.PodDisruptionBudget; pdb != nil {
+		errs = append(errs, validatePodDisruptionBudget(pdb, path.Child("podDisruptionBudget"))...)
+	}
+	if d := deploy.DrainTimeout; d != nil && (d.Duration < 0 || d.Duration > maxProxyDrainTimeout) {
+		errs = append(errs, field.Invalid(path.Child("drainTimeout"), d.Duration.String(),
+			fmt.Sprintf("must be between 0s and %s", maxProxyDrainTimeout)))
+	}
+
+	return errs
+}
+
+func validateIntOrPercent(v *intstr.IntOrString, path *field.Path) field.ErrorList {
+	if n, err := intstr.GetScaledValueFromIntOrPercent(v, 100, false); err != nil || n < 0 {
+		return field.ErrorList{field.Invalid(path, v.String(), "must be a non-negative integer or percentage")}
+	}
+	return nil
+}
+
+func validatePodDisruptionBudget(pdb *ProxyPodDisruptionBudget, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if (pdb.MinAvailable == nil) == (pdb.MaxUnavailable == nil) {
+		errs = append(errs, field.Invalid(path, "", "exactly one of minAvailable and maxUnavailable must be set"))
+	}
+	if pdb.MinAvailable != nil {
+		errs = append(errs, validateIntOrPercent(pdb.MinAvailable, path.Child("minAvailable"))...)
+	}
+	if pdb.MaxUnavailable != nil {
+		errs = append(errs, validateIntOrPercent(pdb.MaxUnavailable, path.Child("maxUnavailable"))...)
+	}
+
+	return errs
+}
+
+func validateResources(resources *corev1.ResourceRequirements, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	for name, q := range resources.Limits {
+		if q.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("limits").Key(string(name)), q.String(),
+				"must be greater than or equal to 0"))
+		}
+	}
+	for name, q := range resources.Requests {
+		if q.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("requests").Key(string(name)), q.String(),
+				"must be greater than or equal to 0"))
+		}
+		if limit, ok := resources.Limits[name]; ok && q.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(path.Child("requests").Key(string(name)), q.String(),
+				fmt.Sprintf("must be less than or equal to the %s limit", name)))
+		}
+	}
+
+	return errs
+}
+
+func validateService(svc *ProxyService, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	serviceType := serviceTypeOf(svc)
+	if !contains(supportedServiceTypes, string(serviceType)) {
+		errs = append(errs, field.NotSupported(path.Child("type"), serviceType, supportedServiceTypes))
+	}
+	for key := range svc.Annotations {
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+			errs = append(errs, field.Invalid(path.Child("annotations").Key(key), key, msg))
+		}
+	}
+	if svc.LoadBalancerClass != nil {
+		if serviceType != corev1.ServiceTypeLoadBalancer {
+			errs = append(errs, field.Invalid(path.Child("loadBalancerClass"), *svc.LoadBalancerClass,
+				"may only be set for a LoadBalancer Service"))
+		}
+		for _, msg := range validation.IsQualifiedName(*svc.LoadBalancerClass) {
+			errs = append(errs, field.Invalid(path.Child("loadBalancerClass"), *svc.LoadBalancerClass, msg))
+		}
+	}
+	if policy := svc.ExternalTrafficPolicy; policy != nil {
+		switch {
+		case *policy != corev1.ServiceExternalTrafficPolicyCluster && *policy != corev1.ServiceExternalTrafficPolicyLocal:
+			errs = append(errs, field.NotSupported(path.Child("externalTrafficPolicy"), *policy, []string{
+				string(corev1.ServiceExternalTrafficPolicyCluster), string(corev1.ServiceExternalTrafficPolicyLocal),
+			}))
+		case serviceType == corev1.ServiceTypeClusterIP:
+			errs = append(errs, field.Invalid(path.Child("externalTrafficPolicy"), *policy,
+				"may only be set for a NodePort or LoadBalancer Service"))
+		}
+	}
+
+	return errs
+}
+
+// unsafeUpdates returns the paths of the fields changed from oldSpec to spec that
+// would recreate or replace the Service of live Gateways, changing their addresses.
+func unsafeUpdates(oldSpec, spec *GatewayClassConfigSpec, path *field.Path) []*field.Path {
+	var res []*field.Path
+
+	if mergeScopeOf(oldSpec) != mergeScopeOf(spec) {
+		res = append(res, path.Child("mergeGateways"))
+	}
+
+	svcPath := path.Child("service")
+	oldSvc, svc := proxyService(oldSpec), proxyService(spec)
+	if serviceTypeOf(oldSvc) != serviceTypeOf(svc) {
+		res = append(res, svcPath.Child("type"))
+	}
+	if stringValue(oldSvc.LoadBalancerClass) != stringValue(svc.LoadBalancerClass) {
+		res = append(res, svcPath.Child("loadBalancerClass"))
+	}
+
+	return res
+}
+
+// proxyService returns the Service configuration of spec, which is empty if unset.
+func proxyService(spec *GatewayClassConfigSpec) *ProxyService {
+	if spec.Service == nil {
+		return &ProxyService{}
+	}
+	return spec.Service
+}
+
+// serviceTypeOf returns the type of svc, defaulting to DefaultProxyServiceType.
+func serviceTypeOf(svc *ProxyService) corev1.ServiceType {
+	if svc.Type == nil {
+		return DefaultProxyServiceType
+	}
+	return *svc.Type
+}
+
+// mergeScopeOf returns the merge scope of spec, which is empty if unset.
+func mergeScopeOf(spec *GatewayClassConfigSpec) MergeGatewaysScope {
+	if spec.MergeGateways == nil {
+		return ""
+	}
+	return *spec.MergeGateways
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// gatewayNames formats the names of gateways, listing at most maxInUseGateways.
+func gatewayNames(gateways []types.NamespacedName) string {
+	var names []string
+	for i, gw := range gateways {
+		if i == maxInUseGateways {
+			names = append(names, fmt.Sprintf("and %d more", len(gateways)-maxInUseGateways))
+			break
+		}
+		names = append(names, gw.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// invalid returns an Invalid error for gcc if errs isn't empty, or nil.
+func invalid(gcc *GatewayClassConfig, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("GatewayClassConfig").GroupKind(), gcc.Name, errs)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newGatewayClassConfig(spec GatewayClassConfigSpec) *GatewayClassConfig {
+	return &GatewayClassConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec:       spec,
+	}
+}
+
+func serviceType(t corev1.ServiceType) *corev1.ServiceType {
+	return &t
+}
+
+func mergeScope(s MergeGatewaysScope) *MergeGatewaysScope {
+	return &s
+}
+
+func TestDefault(t *testing.T) {
+	gcc := newGatewayClassConfig(GatewayClassConfigSpec{})
+	if err := (&GatewayClassConfigWebhook{}).Default(context.Background(), gcc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deploy := gcc.Spec.Deployment; deploy == nil || *deploy.Replicas != DefaultProxyReplicas ||
+		deploy.DrainTimeout.Duration != DefaultProxyDrainTimeout {
+		t.Errorf("expected the defaulted deployment, found %+v", deploy)
+	}
+	if svc := gcc.Spec.Service; svc == nil || *svc.Type != DefaultProxyServiceType {
+		t.Errorf("expected the defaulted service, found %+v", svc)
+	}
+	if gcc.Spec.Foo != "bar" {
+		t.Errorf("expected the defaulted foo, found %q", gcc.Spec.Foo)
+	}
+}
+
+func TestDefaultAutoscaling(t *testing.T) {
+	gcc := newGatewayClassConfig(GatewayClassConfigSpec{
+		Deployment: &ProxyDeployment{Autoscaling: &ProxyAutoscaling{MaxReplicas: 5}},
+	})
+	if err := (&GatewayClassConfigWebhook{}).Default(context.Background(), gcc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	as := gcc.Spec.Deployment.Autoscaling
+	if *as.MinReplicas != DefaultProxyMinReplicas {
+		t.Errorf("expected the defaulted min replicas, found %d", *as.MinReplicas)
+	}
+	if len(as.Metrics) != 1 || as.Metrics[0].Resource == nil || as.Metrics[0].Resource.Name != corev1.ResourceCPU ||
+		*as.Metrics[0].Resource.Target.AverageUtilization != DefaultProxyCPUUtilization {
+		t.Errorf("expected the defaulted CPU utilization metric, found %+v", as.Metrics)
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	negative := int32(-1)
+	lbClass := "example.com/lb"
+	local := corev1.ServiceExternalTrafficPolicyLocal
+	three := int32(3)
+	half := intstr.FromString("50%")
+	one := intstr.FromInt32(1)
+	testCases := []struct {
+		name    string
+		spec    GatewayClassConfigSpec
+		wantErr string
+	}{
+		{
+			name: "valid",
+			spec: GatewayClassConfigSpec{
+				Deployment: &ProxyDeployment{Container: &ProxyContainer{
+					Image: "docker.io/envoyproxy/envoy:v1.28.0",
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+						Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+					},
+				}},
+				Service: &ProxyService{
+					Type:                  serviceType(corev1.ServiceTypeLoadBalancer),
+					Annotations:           map[string]string{"service.beta.kubernetes.io/aws-load-balancer-type": "nlb"},
+					LoadBalancerClass:     &lbClass,
+					ExternalTrafficPolicy: &local,
+				},
+			},
+		},
+		{
+			name: "image with registry port and digest",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{Container: &ProxyContainer{
+				Image: "localhost:5000/proxy@sha256:" + strings.Repeat("a", 64),
+			}}},
+		},
+		{
+			name:    "malformed image",
+			spec:    GatewayClassConfigSpec{Deployment: &ProxyDeployment{Container: &ProxyContainer{Image: "Envoy:latest:v1"}}},
+			wantErr: "spec.deployment.container.image",
+		},
+		{
+			name:    "negative replicas",
+			spec:    GatewayClassConfigSpec{Deployment: &ProxyDeployment{Replicas: &negative}},
+			wantErr: "spec.deployment.replicas",
+		},
+		{
+			name: "negative quantity",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{Container: &ProxyContainer{
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("-1Gi")},
+				},
+			}}},
+			wantErr: "spec.deployment.container.resources.limits[memory]",
+		},
+		{
+			name: "request greater than limit",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{Container: &ProxyContainer{
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+				},
+			}}},
+			wantErr: "spec.deployment.container.resources.requests[cpu]",
+		},
+		{
+			name:    "unknown service type",
+			spec:    GatewayClassConfigSpec{Service: &ProxyService{Type: serviceType(corev1.ServiceTypeExternalName)}},
+			wantErr: "spec.service.type",
+		},
+		{
+			name:    "invalid annotation",
+			spec:    GatewayClassConfigSpec{Service: &ProxyService{Annotations: map[string]string{"invalid key": ""}}},
+			wantErr: "spec.service.annotations[invalid key]",
+		},
+		{
+			name: "load balancer class of a ClusterIP service",
+			spec: GatewayClassConfigSpec{Service: &ProxyService{
+				Type: serviceType(corev1.ServiceTypeClusterIP), LoadBalancerClass: &lbClass,
+			}},
+			wantErr: "spec.service.loadBalancerClass",
+		},
+		{
+			name: "external traffic policy of a ClusterIP service",
+			spec: GatewayClassConfigSpec{Service: &ProxyService{
+				Type: serviceType(corev1.ServiceTypeClusterIP), ExternalTrafficPolicy: &local,
+			}},
+			wantErr: "spec.service.externalTrafficPolicy",
+		},
+		{
+			name: "autoscaling and disruption budget",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{
+				Autoscaling:         &ProxyAutoscaling{MinReplicas: &three, MaxReplicas: 10},
+				PodDisruptionBudget: &ProxyPodDisruptionBudget{MinAvailable: &half},
+			}},
+		},
+		{
+			name: "min replicas greater than max replicas",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{
+				Autoscaling: &ProxyAutoscaling{MinReplicas: &three, MaxReplicas: 2},
+			}},
+			wantErr: "spec.deployment.autoscaling.minReplicas",
+		},
+		{
+			name: "disruption budget with min available and max unavailable",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{
+				PodDisruptionBudget: &ProxyPodDisruptionBudget{MinAvailable: &half, MaxUnavailable: &one},
+			}},
+			wantErr: "spec.deployment.podDisruptionBudget",
+		},
+		{
+			name: "malformed disruption budget percentage",
+			spec: GatewayClassConfigSpec{Deployment: &ProxyDeployment{
+				PodDisruptionBudget: &ProxyPodDisruptionBudget{MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "half"}},
+			}},
+			wantErr: "spec.deployment.podDisruptionBudget.maxUnavailable",
+		},
+		{
+			name:    "negative drain timeout",
+			spec:    GatewayClassConfigSpec{Deployment: &ProxyDeployment{DrainTimeout: &metav1.Duration{Duration: -time.Second}}},
+			wantErr: "spec.deployment.drainTimeout",
+		},
+		{
+			name:    "unknown merge scope",
+			spec:    GatewayClassConfigSpec{MergeGateways: mergeScope("Cluster")},
+			wantErr: "spec.mergeGateways",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := (&GatewayClassConfigWebhook{}).ValidateCreate(context.Background(), newGatewayClassConfig(tc.spec))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected an error for %s, found %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	live := []types.NamespacedName{{Namespace: "default", Name: "gw"}}
+	testCases := []struct {
+		name     string
+		gateways []types.NamespacedName
+		oldSpec  GatewayClassConfigSpec
+		spec     GatewayClassConfigSpec
+		wantErr  bool
+	}{
+		{
+			name:    "service type change without gateways",
+			oldSpec: GatewayClassConfigSpec{Service: &ProxyService{Type: serviceType(corev1.ServiceTypeLoadBalancer)}},
+			spec:    GatewayClassConfigSpec{Service: &ProxyService{Type: serviceType(corev1.ServiceTypeClusterIP)}},
+		},
+		{
+			name:     "service type change with live gateways",
+			gateways: live,
+			oldSpec:  GatewayClassConfigSpec{Service: &ProxyService{Type: serviceType(corev1.ServiceTypeLoadBalancer)}},
+			spec:     GatewayClassConfigSpec{Service: &ProxyService{Type: serviceType(corev1.ServiceTypeClusterIP)}},
+			wantErr:  true,
+		},
+		{
+			name:     "defaulted service type with live gateways",
+			gateways: live,
+			spec:     GatewayClassConfigSpec{Service: &ProxyService{Type: serviceType(DefaultProxyServiceType)}},
+		},
+		{
+			name:     "image change with live gateways",
+			gateways: live,
+			oldSpec:  GatewayClassConfigSpec{Deployment: &ProxyDeployment{Container: &ProxyContainer{Image: "envoy:v1"}}},
+			spec:     GatewayClassConfigSpec{Deployment: &ProxyDeployment{Container: &ProxyContainer{Image: "envoy:v2"}}},
+		},
+		{
+			name:     "merging live gateways",
+			gateways: live,
+			spec:     GatewayClassConfigSpec{MergeGateways: mergeScope(MergeGatewaysNamespace)},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &GatewayClassConfigWebhook{
+				GatewaysInUse: func(context.Context, types.NamespacedName) ([]types.NamespacedName, error) {
+					return tc.gateways, nil
+				},
+			}
+			_, err := w.ValidateUpdate(context.Background(), newGatewayClassConfig(tc.oldSpec), newGatewayClassConfig(tc.spec))
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "default/gw") {
+					t.Errorf("expected an error listing the live gateway, found %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories=gateway-api,shortName=gwc
+
+// GatewayConfig is the Schema for the gatewayconfigs API. A GatewayConfig overrides the
+// GatewayClassConfig of the class of the Gateways in its namespace that reference it.
+type GatewayConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GatewayConfigSpec `json:"spec,omitempty"`
+}
+
+// GatewayConfigSpec defines the desired state of GatewayConfig. Unset fields keep the
+// value of the GatewayClassConfig.
+type GatewayConfigSpec struct {
+	// Deployment overrides the proxy Deployment configuration of the Gateway.
+	//
+	// +optional
+	Deployment *ProxyDeployment `json:"deployment,omitempty"`
+
+	// Service overrides the Service configuration of the Gateway. Annotations are added
+	// to those of the GatewayClassConfig.
+	//
+	// +optional
+	Service *ProxyService `json:"service,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GatewayConfigList contains a list of GatewayConfig
+type GatewayConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GatewayConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GatewayConfig{}, &GatewayConfigList{})
+}
@@ -17,10 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"strings"
+
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 	"solo.io/sample-gateway-manager/internal/model"
 
@@ -28,91 +34,217 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	uberzap "go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	mgrcfgv1a1 "solo.io/sample-gateway-manager/api/config/v1alpha1"
 	cfgv1a1 "solo.io/sample-gateway-manager/api/v1alpha1"
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/admin"
+	"solo.io/sample-gateway-manager/internal/config"
+	"solo.io/sample-gateway-manager/internal/events"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
 	"solo.io/sample-gateway-manager/internal/kubernetes"
+	"solo.io/sample-gateway-manager/internal/status"
+	"solo.io/sample-gateway-manager/internal/tracing"
+	"solo.io/sample-gateway-manager/internal/xds"
 )
 
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
-
-	defCtrlNameFlag = fmt.Sprintf("%s/gateway-manager", cfgv1a1.GroupVersion.Group)
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1a2.AddToScheme(scheme))
 	utilruntime.Must(gwapiv1b1.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
 	utilruntime.Must(cfgv1a1.AddToScheme(scheme))
+	utilruntime.Must(cfgv1b1.AddToScheme(scheme))
 }
 
 func main() {
-	var ctrlName, metricsAddr, probeAddr string
+	var configFile, metricsAddr, probeAddr, adminAddr string
 	var enableLeaderElection bool
-	flag.StringVar(&ctrlName, "controller-name", defCtrlNameFlag, "The name of the controller that manages Gateways of this class.")
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	var tracingOpts tracing.Options
+	flag.StringVar(&configFile, "config", "",
+		"The path of the manager configuration file. "+
+			"Flags set on the command line take precedence over the values of the file.")
+	var ctrlNames []string
+	flag.Func("controller-name",
+		fmt.Sprintf("The name of a controller that manages Gateways of its classes. May be repeated to serve several "+
+			"controllers. (default %q)", config.DefaultControllerName),
+		func(name string) error {
+			ctrlNames = append(ctrlNames, name)
+			return nil
+		})
+	flag.StringVar(&metricsAddr, "metrics-bind-address", config.DefaultMetricsBindAddress, "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", config.DefaultHealthBindAddress, "The address the probe endpoint binds to.")
+	flag.StringVar(&adminAddr, "admin-bind-address", config.DefaultAdminBindAddress,
+		"The address the admin server binds to. Requests are authenticated unless it is a loopback address. "+
+			"Set to \"0\" to disable the admin server.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
-	opts := zap.Options{
-		Development: true,
-	}
+	flag.StringVar(&tracingOpts.Endpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC collector traces are exported to. Tracing is disabled if empty.")
+	flag.BoolVar(&tracingOpts.Insecure, "otlp-insecure", false,
+		"Disable TLS for the connection to the OTLP collector.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "trace-sample-ratio", 1,
+		"The ratio of reconciles traced, between 0 and 1.")
+	featureGates := model.NewFeatureGates()
+	flag.Var(featureGates, "feature-gates",
+		"A set of key=value pairs that describe feature gates for alpha/experimental features. Options are:\n"+
+			strings.Join(featureGates.KnownFeatures(), "\n"))
+	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	// overrideFromFlags applies the flags set on the command line to a manager configuration.
+	overrideFromFlags := func(c *mgrcfgv1a1.ManagerConfiguration) {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "controller-name":
+				config.SetControllerNames(c, ctrlNames)
+			case "metrics-bind-address":
+				c.Metrics.BindAddress = metricsAddr
+			case "health-probe-bind-address":
+				c.Health.BindAddress = probeAddr
+			case "admin-bind-address":
+				c.Admin.BindAddress = adminAddr
+			case "leader-elect":
+				c.LeaderElection.LeaderElect = &enableLeaderElection
+			case "zap-log-level":
+				c.Logging.Level = f.Value.String()
+			case "zap-devel":
+				c.Logging.Development = &opts.Development
+			case "zap-encoder":
+				c.Logging.Format = strings.ToLower(f.Value.String())
+			case "feature-gates":
+				if c.FeatureGates == nil {
+					c.FeatureGates = map[string]bool{}
+				}
+				for name, enabled := range featureGates.Overrides() {
+					c.FeatureGates[name] = enabled
+				}
+			}
+		})
+	}
+
+	mgrCfg := config.Default()
+	if configFile != "" {
+		var err error
+		if mgrCfg, err = config.Load(configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to load config file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	overrideFromFlags(mgrCfg)
+	if err := config.Validate(mgrCfg); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The level was validated above.
+	level, _ := config.ParseLogLevel(mgrCfg.Logging.Level)
+	logLevel := uberzap.NewAtomicLevelAt(level)
+	opts.Level = logLevel
+	opts.Development = *mgrCfg.Logging.Development
+	if mgrCfg.Logging.Format == config.LogFormatConsole {
+		zap.ConsoleEncoder(opts.EncoderConfigOptions...)(&opts)
+	} else {
+		zap.JSONEncoder(opts.EncoderConfigOptions...)(&opts)
+	}
+
 	logger := zap.New(zap.UseFlagOptions(&opts))
 	ctrl.SetLogger(logger)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "96ab2193.solo.io",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
-	})
+	cfgWatcher := config.NewWatcher(configFile, mgrCfg, logLevel, logger)
+	cfgWatcher.Overrides = overrideFromFlags
+
+	mgrOpts := config.ManagerOptions(mgrCfg)
+	mgrOpts.Scheme = scheme
+	mgrOpts.WebhookServer = webhook.NewServer(webhook.Options{Port: 9443})
+	// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+	// when the Manager ends. This requires the binary to immediately end when the
+	// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+	// speeds up voluntary leader transitions as the new leader don't have to wait
+	// LeaseDuration time first.
+	//
+	// In the default scaffold provided, the program ends immediately after
+	// the manager stops, so would be fine to enable this option. However,
+	// if you are doing or is intended to do any operation such as perform cleanups
+	// after the manager stops then its usage might be unsafe.
+	// mgrOpts.LeaderElectionReleaseOnCancel = true
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOpts)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if err := mgr.Add(cfgWatcher); err != nil {
+		setupLog.Error(err, "unable to set up config watcher")
+		os.Exit(1)
+	}
+
+	// The feature gates were validated above.
+	featureGates = model.NewFeatureGates()
+	_ = featureGates.SetFromMap(mgrCfg.FeatureGates)
+
 	cfg := &model.ManagerConfig{
-		ControllerName: ctrlName,
+		Controllers:         config.ControllerConfigs(mgrCfg),
+		FeatureGates:        featureGates,
+		ProxyImage:          mgrCfg.Proxy.Image,
+		XDSAddress:          mgrCfg.XDS.Address,
+		CertificateValidity: mgrCfg.XDS.CertificateValidity.Duration,
 	}
 
 	procChan := make(chan event.GenericEvent)
 
 	store := kubernetes.NewObjectStore()
 
+	recorder := events.NewRecorder(mgr.GetEventRecorderFor("sample-gateway-manager"), events.DefaultTTL)
+	statusUpdater := status.NewUpdater(mgr.GetClient(), logger, recorder)
+	if err := mgr.Add(statusUpdater); err != nil {
+		setupLog.Error(err, "unable to set up status updater")
+		os.Exit(1)
+	}
+
+	// The admission and conversion webhooks require a serving certificate, so they can be
+	// disabled to run the manager locally.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&cfgv1b1.GatewayClassConfigWebhook{
+			GatewaysInUse: func(_ context.Context, nsName types.NamespacedName) ([]types.NamespacedName, error) {
+				return store.GatewaysUsingConfig(cfg, nsName), nil
+			},
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GatewayClassConfig")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&kubernetes.GatewayClassConfigReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Config: cfg,
-		Log:    logger,
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        cfg,
+		Log:           logger,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "name", "GatewayClassConfig")
 		os.Exit(1)
 	}
-
-	if err = (&kubernetes.GatewayClassReconciler{
+	if err = (&kubernetes.GatewayConfigReconciler{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		Config:        cfg,
@@ -120,11 +252,10 @@ func main() {
 		ObjectStore:   store,
 		ProcessorChan: procChan,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "name", "GatewayClass")
+		setupLog.Error(err, "unable to create controller", "name", "GatewayConfig")
 		os.Exit(1)
 	}
-
-	if err = (&kubernetes.GatewayReconciler{
+	if err = (&kubernetes.ServiceReconciler{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		Config:        cfg,
@@ -132,22 +263,127 @@ func main() {
 		ObjectStore:   store,
 		ProcessorChan: procChan,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "name", "Gateway")
+		setupLog.Error(err, "unable to create controller", "name", "Service")
 		os.Exit(1)
 	}
 
-	if err = (&kubernetes.Processor{
-		Client:      mgr.GetClient(),
-		Scheme:      mgr.GetScheme(),
-		Config:      cfg,
-		Log:         logger,
-		ObjectStore: store,
-		UpdateChan:  procChan,
+	// setupController registers the controller of a Gateway API kind. Controllers are
+	// registered by the CRD reconciler once the CRD of their kind is installed.
+	setupController := func(crd gatewayapi.CRD) error {
+		switch crd.Kind {
+		case "GatewayClass":
+			return (&kubernetes.GatewayClassReconciler{
+				Client:        mgr.GetClient(),
+				Scheme:        mgr.GetScheme(),
+				Config:        cfg,
+				Log:           logger,
+				APIVersion:    crd.APIVersion(),
+				ObjectStore:   store,
+				ProcessorChan: procChan,
+			}).SetupWithManager(mgr)
+		case "Gateway":
+			return (&kubernetes.GatewayReconciler{
+				Client:        mgr.GetClient(),
+				Scheme:        mgr.GetScheme(),
+				Config:        cfg,
+				Log:           logger,
+				APIVersion:    crd.APIVersion(),
+				ObjectStore:   store,
+				ProcessorChan: procChan,
+			}).SetupWithManager(mgr)
+		default:
+			return (&kubernetes.RouteReconciler{
+				Client:      mgr.GetClient(),
+				Scheme:      mgr.GetScheme(),
+				Config:      cfg,
+				Log:         logger,
+				Kind:        crd.Kind,
+				ObjectStore: store,
+			}).SetupWithManager(mgr)
+		}
+	}
+
+	kinds := []gwapiv1.Kind{"GatewayClass", "Gateway"}
+	for _, kind := range kubernetes.GatedRouteKinds {
+		if !featureGates.Enabled(model.Feature(kind)) {
+			setupLog.Info("feature gate disabled; skipping controller", "name", kind)
+			continue
+		}
+		kinds = append(kinds, kind)
+		cfg.RouteKinds = append(cfg.RouteKinds, kind)
+	}
+
+	if err = (&kubernetes.CRDReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        cfg,
+		Log:           logger,
+		Kinds:         kinds,
+		Setup:         setupController,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
 	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "name", "CustomResourceDefinition")
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	// The CA is loaded from the API server, since the cache isn't started yet. The proxies
+	// aren't bootstrapped without an xDS server address, so no CA is needed.
+	var ca *xds.CA
+	if mgrCfg.XDS.Address != "" {
+		caSecret := types.NamespacedName{Namespace: mgrCfg.XDS.CASecret.Namespace, Name: mgrCfg.XDS.CASecret.Name}
+		if ca, err = kubernetes.LoadCA(ctx, mgr.GetClient(), mgr.GetAPIReader(), caSecret); err != nil {
+			setupLog.Error(err, "unable to load xDS CA")
+			os.Exit(1)
+		}
+
+		// The address was validated with the manager configuration.
+		host, _, _ := net.SplitHostPort(mgrCfg.XDS.Address)
+		if err := mgr.Add(&kubernetes.ServerCertificateIssuer{
+			Client:   mgr.GetClient(),
+			Reader:   mgr.GetAPIReader(),
+			Log:      logger.WithName("xds server certificate"),
+			CA:       ca,
+			Secret:   types.NamespacedName{Namespace: mgrCfg.XDS.ServerSecret.Namespace, Name: mgrCfg.XDS.ServerSecret.Name},
+			Host:     host,
+			Validity: mgrCfg.XDS.CertificateValidity.Duration,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up xDS server certificate issuer")
+			os.Exit(1)
+		}
+	}
+
+	processor := &kubernetes.Processor{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        cfg,
+		Log:           logger,
+		ObjectStore:   store,
+		UpdateChan:    procChan,
+		StatusUpdater: statusUpdater,
+		CA:            ca,
+		APIReader:     mgr.GetAPIReader(),
+	}
+	if err = processor.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "name", "Processor")
 		os.Exit(1)
 	}
 
+	if mgrCfg.Admin.BindAddress != "0" {
+		adminServer := admin.NewServer(mgrCfg.Admin.BindAddress, mgr.GetClient(), logger)
+		adminServer.Handle("config", cfgWatcher)
+		adminServer.HandleWritable("loglevel", cfgWatcher.LogLevelHandler())
+		adminServer.HandleJSON("store", func() interface{} { return store.Snapshot() })
+		adminServer.HandleJSON("infrastructure", func() interface{} { return processor.Infrastructure() })
+		adminServer.HandleJSON("certificates", func() interface{} { return processor.Certificates() })
+		if err := mgr.Add(adminServer); err != nil {
+			setupLog.Error(err, "unable to set up admin server")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -157,8 +393,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	tracingOpts.ServiceName = "sample-gateway-manager"
+	shutdownTracing, err := tracing.Setup(ctx, tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctx)
+	// Flush the pending spans with a fresh context, since ctx is done on exit.
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admin serves the debug endpoints of the manager.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DebugPath is the path prefix of the debug endpoints.
+	DebugPath = "/debug/"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Server is the admin server of the manager. It serves the registered debug endpoints
+// and an index of them at DebugPath. Requests are authenticated unless the server binds
+// a loopback address.
+type Server struct {
+	// BindAddress is the address the server binds to.
+	BindAddress string
+	// Client reviews the bearer tokens and permissions of the requests when the server
+	// doesn't bind a loopback address.
+	Client client.Client
+	Log    logr.Logger
+
+	mux   *http.ServeMux
+	paths []string
+}
+
+// NewServer returns an admin server binding bindAddress.
+func NewServer(bindAddress string, c client.Client, log logr.Logger) *Server {
+	s := &Server{
+		BindAddress: bindAddress,
+		Client:      c,
+		Log:         log.WithName("admin server"),
+		mux:         http.NewServeMux(),
+	}
+	s.mux.HandleFunc(DebugPath, s.serveIndex)
+	return s
+}
+
+// Handle registers h for the debug endpoint of name, served at DebugPath + name.
+func (s *Server) Handle(name string, h http.Handler) {
+	path := DebugPath + name
+	s.paths = append(s.paths, path)
+	s.mux.Handle(path, getOnly(h))
+}
+
+// HandleWritable registers h for the debug endpoint of name, served at DebugPath + name,
+// without restricting the request method. h must reject the methods it doesn't serve.
+func (s *Server) HandleWritable(name string, h http.Handler) {
+	path := DebugPath + name
+	s.paths = append(s.paths, path)
+	s.mux.Handle(path, h)
+}
+
+// HandleJSON registers the debug endpoint of name serving the value returned by fn as JSON.
+func (s *Server) HandleJSON(name string, fn func() interface{}) {
+	s.Handle(name, http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		s.writeJSON(rw, fn())
+	}))
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable so the debug endpoints
+// are served by all replicas.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (s *Server) Start(ctx context.Context) error {
+	handler := http.Handler(s.mux)
+	loopback, err := isLoopback(s.BindAddress)
+	if err != nil {
+		return err
+	}
+	if !loopback {
+		handler = s.authenticate(handler)
+	}
+
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		s.Log.Info("serving debug endpoints", "address", s.BindAddress, "authenticated", !loopback)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("failed to serve debug endpoints: %w", err)
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// serveIndex serves the paths of the debug endpoints.
+func (s *Server) serveIndex(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != DebugPath {
+		http.NotFound(rw, req)
+		return
+	}
+	paths := append([]string{}, s.paths...)
+	sort.Strings(paths)
+	s.writeJSON(rw, paths)
+}
+
+func (s *Server) writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		s.Log.Error(err, "failed to encode debug response")
+	}
+}
+
+// getOnly rejects requests to h with a method other than GET.
+func getOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.ServeHTTP(rw, req)
+	})
+}
+
+// isLoopback returns true if addr binds a loopback address only.
+func isLoopback(addr string) (bool, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false, fmt.Errorf("invalid admin bind address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return true, nil
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback(), nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer("127.0.0.1:0", nil, logr.Discard())
+	s.HandleJSON("store", func() interface{} { return map[string]string{"gateway": "default/test"} })
+
+	testCases := map[string]struct {
+		method     string
+		path       string
+		wantStatus int
+		wantBody   interface{}
+	}{
+		"index": {
+			method:     http.MethodGet,
+			path:       DebugPath,
+			wantStatus: http.StatusOK,
+			wantBody:   []interface{}{"/debug/store"},
+		},
+		"json endpoint": {
+			method:     http.MethodGet,
+			path:       "/debug/store",
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]interface{}{"gateway": "default/test"},
+		},
+		"unknown endpoint": {
+			method:     http.MethodGet,
+			path:       "/debug/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+		"method not allowed": {
+			method:     http.MethodPost,
+			path:       "/debug/store",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, found %d", tc.wantStatus, rec.Code)
+			}
+			if tc.wantBody == nil {
+				return
+			}
+			var body interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if got, want := mustMarshal(t, body), mustMarshal(t, tc.wantBody); got != want {
+				t.Errorf("expected body %s, found %s", want, got)
+			}
+		})
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	testCases := map[string]struct {
+		addr    string
+		want    bool
+		wantErr bool
+	}{
+		"ipv4 loopback": {addr: "127.0.0.1:9090", want: true},
+		"ipv6 loopback": {addr: "[::1]:9090", want: true},
+		"localhost":     {addr: "localhost:9090", want: true},
+		"all addresses": {addr: ":9090", want: false},
+		"pod address":   {addr: "10.0.0.1:9090", want: false},
+		"invalid":       {addr: "localhost", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := isLoopback(tc.addr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, found %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %v, found %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	// The reviewer authenticates the "valid" and "forbidden" tokens, and authorizes
+	// the user of the "valid" token.
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				review.Status.Authenticated = review.Spec.Token == "valid" || review.Spec.Token == "forbidden"
+				review.Status.User.Username = review.Spec.Token
+			case *authorizationv1.SubjectAccessReview:
+				review.Status.Allowed = review.Spec.User == "valid" &&
+					review.Spec.NonResourceAttributes.Path == "/debug/store" &&
+					review.Spec.NonResourceAttributes.Verb == "get"
+			}
+			return nil
+		},
+	}).Build()
+	s := NewServer(":9090", c, logr.Discard())
+	handler := s.authenticate(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := map[string]struct {
+		authorization string
+		wantStatus    int
+	}{
+		"no token":        {wantStatus: http.StatusUnauthorized},
+		"not bearer":      {authorization: "Basic dXNlcg==", wantStatus: http.StatusUnauthorized},
+		"unauthenticated": {authorization: "Bearer invalid", wantStatus: http.StatusUnauthorized},
+		"unauthorized":    {authorization: "Bearer forbidden", wantStatus: http.StatusForbidden},
+		"authorized":      {authorization: "Bearer valid", wantStatus: http.StatusOK},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/store", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("expected status %d, found %d", tc.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return string(b)
+}
@@ -0,0 +1,405 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/yaml"
+
+	mgrcfgv1a1 "solo.io/sample-gateway-manager/api/config/v1alpha1"
+	cfgv1a1 "solo.io/sample-gateway-manager/api/v1alpha1"
+	"solo.io/sample-gateway-manager/internal/model"
+)
+
+const (
+	DefaultLeaderElectionID     = "96ab2193.solo.io"
+	DefaultMetricsBindAddress   = ":8080"
+	DefaultHealthBindAddress    = ":8081"
+	DefaultAdminBindAddress     = "127.0.0.1:9090"
+	DefaultXDSCASecretName      = "sample-gateway-controller-xds-ca"
+	DefaultXDSCASecretNamespace = "sample-gateway-controller-system"
+	DefaultXDSServerSecretName  = "sample-gateway-controller-xds-server"
+	DefaultCertValidity         = 24 * time.Hour
+	DefaultProxyImage           = "docker.io/envoyproxy/envoy:v1.28.0"
+	DefaultLogLevel             = "info"
+	LogFormatJSON               = "json"
+	LogFormatConsole            = "console"
+	defaultLeaseDuration        = 15 * time.Second
+	defaultRenewDeadline        = 10 * time.Second
+	defaultRetryPeriod          = 2 * time.Second
+	disabledBindAddress         = "0"
+	minCertValidity             = time.Hour
+	maxCertValidity             = 365 * 24 * time.Hour
+)
+
+// DefaultControllerName is the default name of the controller that manages Gateways.
+var DefaultControllerName = fmt.Sprintf("%s/gateway-manager", cfgv1a1.GroupVersion.Group)
+
+// controllerNameRegex matches the format of a GatewayClass controllerName.
+var controllerNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*\/[A-Za-z0-9\/\-._~%!$&'()*+,;=:]+$`)
+
+// Default returns a manager configuration with all defaults set.
+func Default() *mgrcfgv1a1.ManagerConfiguration {
+	cfg := &mgrcfgv1a1.ManagerConfiguration{}
+	SetDefaults(cfg)
+	return cfg
+}
+
+// Load reads, defaults and validates the manager configuration file at path.
+func Load(path string) (*mgrcfgv1a1.ManagerConfiguration, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := new(mgrcfgv1a1.ManagerConfiguration)
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+	if cfg.APIVersion != mgrcfgv1a1.GroupVersion.String() || cfg.Kind != mgrcfgv1a1.ManagerConfigurationKind {
+		return nil, fmt.Errorf("unsupported config file %s: expected apiVersion %q and kind %q, found %q and %q",
+			path, mgrcfgv1a1.GroupVersion, mgrcfgv1a1.ManagerConfigurationKind, cfg.APIVersion, cfg.Kind)
+	}
+
+	SetDefaults(cfg)
+	if err := Validate(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// SetDefaults sets the default value of all unset fields of cfg.
+func SetDefaults(cfg *mgrcfgv1a1.ManagerConfiguration) {
+	cfg.APIVersion = mgrcfgv1a1.GroupVersion.String()
+	cfg.Kind = mgrcfgv1a1.ManagerConfigurationKind
+
+	if len(cfg.Controllers) == 0 {
+		if cfg.ControllerName == "" {
+			cfg.ControllerName = DefaultControllerName
+		}
+		cfg.Controllers = []mgrcfgv1a1.ControllerConfiguration{{Name: cfg.ControllerName}}
+	}
+
+	le := &cfg.LeaderElection
+	if le.LeaderElect == nil {
+		le.LeaderElect = new(bool)
+	}
+	if le.ResourceName == "" {
+		le.ResourceName = DefaultLeaderElectionID
+	}
+	if le.LeaseDuration == nil {
+		le.LeaseDuration = &metav1.Duration{Duration: defaultLeaseDuration}
+	}
+	if le.RenewDeadline == nil {
+		le.RenewDeadline = &metav1.Duration{Duration: defaultRenewDeadline}
+	}
+	if le.RetryPeriod == nil {
+		le.RetryPeriod = &metav1.Duration{Duration: defaultRetryPeriod}
+	}
+
+	if cfg.Metrics.BindAddress == "" {
+		cfg.Metrics.BindAddress = DefaultMetricsBindAddress
+	}
+	if cfg.Health.BindAddress == "" {
+		cfg.Health.BindAddress = DefaultHealthBindAddress
+	}
+	if cfg.Admin.BindAddress == "" {
+		cfg.Admin.BindAddress = DefaultAdminBindAddress
+	}
+	xds := &cfg.XDS
+	if xds.CASecret.Namespace == "" {
+		xds.CASecret.Namespace = DefaultXDSCASecretNamespace
+	}
+	if xds.CASecret.Name == "" {
+		xds.CASecret.Name = DefaultXDSCASecretName
+	}
+	if xds.ServerSecret.Namespace == "" {
+		xds.ServerSecret.Namespace = xds.CASecret.Namespace
+	}
+	if xds.ServerSecret.Name == "" {
+		xds.ServerSecret.Name = DefaultXDSServerSecretName
+	}
+	if xds.CertificateValidity == nil {
+		xds.CertificateValidity = &metav1.Duration{Duration: DefaultCertValidity}
+	}
+	if cfg.Proxy.Image == "" {
+		cfg.Proxy.Image = DefaultProxyImage
+	}
+
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = DefaultLogLevel
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = LogFormatJSON
+	}
+	if cfg.Logging.Development == nil {
+		cfg.Logging.Development = new(bool)
+	}
+}
+
+// Validate returns an error if cfg is not a valid defaulted manager configuration.
+func Validate(cfg *mgrcfgv1a1.ManagerConfiguration) error {
+	var errs field.ErrorList
+
+	errs = append(errs, validateControllers(cfg)...)
+
+	for i, ns := range cfg.WatchNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(field.NewPath("watchNamespaces").Index(i), ns, msg))
+		}
+	}
+
+	lePath := field.NewPath("leaderElection")
+	le := cfg.LeaderElection
+	for _, d := range []struct {
+		name  string
+		value *metav1.Duration
+	}{
+		{"leaseDuration", le.LeaseDuration},
+		{"renewDeadline", le.RenewDeadline},
+		{"retryPeriod", le.RetryPeriod},
+	} {
+		if d.value.Duration <= 0 {
+			errs = append(errs, field.Invalid(lePath.Child(d.name), d.value.Duration.String(), "must be greater than 0"))
+		}
+	}
+	if le.RenewDeadline.Duration >= le.LeaseDuration.Duration {
+		errs = append(errs, field.Invalid(lePath.Child("renewDeadline"), le.RenewDeadline.Duration.String(),
+			"must be less than leaseDuration"))
+	}
+
+	if cfg.Metrics.BindAddress != disabledBindAddress {
+		errs = append(errs, validateBindAddress(field.NewPath("metrics", "bindAddress"), cfg.Metrics.BindAddress)...)
+	}
+	errs = append(errs, validateBindAddress(field.NewPath("health", "bindAddress"), cfg.Health.BindAddress)...)
+	if cfg.Admin.BindAddress != disabledBindAddress {
+		errs = append(errs, validateBindAddress(field.NewPath("admin", "bindAddress"), cfg.Admin.BindAddress)...)
+	}
+	errs = append(errs, validateXDS(cfg.XDS)...)
+
+	if _, err := ParseLogLevel(cfg.Logging.Level); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("logging", "level"), cfg.Logging.Level, err.Error()))
+	}
+	if cfg.Logging.Format != LogFormatJSON && cfg.Logging.Format != LogFormatConsole {
+		errs = append(errs, field.NotSupported(field.NewPath("logging", "format"), cfg.Logging.Format,
+			[]string{LogFormatJSON, LogFormatConsole}))
+	}
+
+	if err := model.NewFeatureGates().SetFromMap(cfg.FeatureGates); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("featureGates"), cfg.FeatureGates, err.Error()))
+	}
+
+	return errs.ToAggregate()
+}
+
+func validateXDS(xds mgrcfgv1a1.XDSConfiguration) field.ErrorList {
+	path := field.NewPath("xds")
+	var errs field.ErrorList
+
+	// The proxies aren't bootstrapped without an address.
+	if xds.Address != "" {
+		if host, port, err := net.SplitHostPort(xds.Address); err != nil {
+			errs = append(errs, field.Invalid(path.Child("address"), xds.Address, err.Error()))
+		} else {
+			// The proxies match the DNS names of the xDS server certificate against the host.
+			if net.ParseIP(host) != nil {
+				errs = append(errs, field.Invalid(path.Child("address"), xds.Address, "must have a DNS name host"))
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(host) {
+				errs = append(errs, field.Invalid(path.Child("address"), xds.Address, msg))
+			}
+			if n, err := strconv.Atoi(port); err != nil || validation.IsValidPortNum(n) != nil {
+				errs = append(errs, field.Invalid(path.Child("address"), xds.Address, "must have a valid port"))
+			}
+		}
+	}
+
+	errs = append(errs, validateSecretReference(xds.CASecret, path.Child("caSecret"))...)
+	errs = append(errs, validateSecretReference(xds.ServerSecret, path.Child("serverSecret"))...)
+	// The server certificate would overwrite the CA.
+	if xds.ServerSecret == xds.CASecret {
+		errs = append(errs, field.Invalid(path.Child("serverSecret"), xds.ServerSecret, "must differ from caSecret"))
+	}
+
+	if v := xds.CertificateValidity.Duration; v < minCertValidity || v > maxCertValidity {
+		errs = append(errs, field.Invalid(path.Child("certificateValidity"), v.String(),
+			fmt.Sprintf("must be between %s and %s", minCertValidity, maxCertValidity)))
+	}
+
+	return errs
+}
+
+func validateSecretReference(secret mgrcfgv1a1.SecretReference, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(secret.Namespace) {
+		errs = append(errs, field.Invalid(path.Child("namespace"), secret.Namespace, msg))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(secret.Name) {
+		errs = append(errs, field.Invalid(path.Child("name"), secret.Name, msg))
+	}
+	return errs
+}
+
+func validateControllers(cfg *mgrcfgv1a1.ManagerConfiguration) field.ErrorList {
+	var errs field.ErrorList
+
+	if cfg.ControllerName != "" && (len(cfg.Controllers) != 1 || cfg.Controllers[0].Name != cfg.ControllerName) {
+		errs = append(errs, field.Forbidden(field.NewPath("controllerName"),
+			"may not be set together with different controllers"))
+	}
+	if len(cfg.Controllers) == 0 {
+		errs = append(errs, field.Required(field.NewPath("controllers"), "at least one controller is required"))
+	}
+
+	names := map[string]bool{}
+	for i, c := range cfg.Controllers {
+		path := field.NewPath("controllers").Index(i)
+		if !controllerNameRegex.MatchString(c.Name) {
+			errs = append(errs, field.Invalid(path.Child("name"), c.Name,
+				"must be a domain prefixed path, e.g. example.com/gateway-manager"))
+		}
+		if names[c.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), c.Name))
+		}
+		names[c.Name] = true
+
+		if ref := c.DefaultGatewayClassConfig; ref != nil {
+			refPath := path.Child("defaultGatewayClassConfig")
+			for _, msg := range validation.IsDNS1123Label(ref.Namespace) {
+				errs = append(errs, field.Invalid(refPath.Child("namespace"), ref.Namespace, msg))
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+				errs = append(errs, field.Invalid(refPath.Child("name"), ref.Name, msg))
+			}
+		}
+	}
+
+	return errs
+}
+
+// SetControllerNames sets the controllers of cfg to the named controllers, keeping the
+// configuration of the controllers of cfg with the same name.
+func SetControllerNames(cfg *mgrcfgv1a1.ManagerConfiguration, names []string) {
+	existing := map[string]mgrcfgv1a1.ControllerConfiguration{}
+	for _, c := range cfg.Controllers {
+		existing[c.Name] = c
+	}
+
+	cfg.ControllerName = ""
+	cfg.Controllers = nil
+	for _, name := range names {
+		c, ok := existing[name]
+		if !ok {
+			c = mgrcfgv1a1.ControllerConfiguration{Name: name}
+		}
+		cfg.Controllers = append(cfg.Controllers, c)
+	}
+}
+
+// ControllerConfigs returns the configurations of the controllers of cfg.
+func ControllerConfigs(cfg *mgrcfgv1a1.ManagerConfiguration) []model.ControllerConfig {
+	var res []model.ControllerConfig
+	for _, c := range cfg.Controllers {
+		mc := model.ControllerConfig{Name: c.Name}
+		if ref := c.DefaultGatewayClassConfig; ref != nil {
+			mc.DefaultGatewayClassConfig = &types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+		}
+		res = append(res, mc)
+	}
+	return res
+}
+
+func validateBindAddress(path *field.Path, addr string) field.ErrorList {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, addr, err.Error())}
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+		return field.ErrorList{field.Invalid(path, addr, "port must be a number between 0 and 65535")}
+	}
+	return nil
+}
+
+// ParseLogLevel parses a log level of "debug", "info", "error", or an integer
+// verbosity greater than 0.
+func ParseLogLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+
+	v, err := strconv.Atoi(level)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("must be one of debug, info, error, or an integer greater than 0")
+	}
+	return zapcore.Level(int8(-v)), nil
+}
+
+// FormatLogLevel formats level as parsed by ParseLogLevel.
+func FormatLogLevel(level zapcore.Level) string {
+	if level < zapcore.DebugLevel {
+		return strconv.Itoa(-int(level))
+	}
+	return level.String()
+}
+
+// ManagerOptions returns the controller-runtime manager options for cfg. The controllers
+// run on every replica to keep the standby replicas warm, and only the runnables that
+// write, e.g. the status updater, wait for leader election.
+func ManagerOptions(cfg *mgrcfgv1a1.ManagerConfiguration) ctrl.Options {
+	le := cfg.LeaderElection
+	needLeaderElection := false
+	opts := ctrl.Options{
+		Metrics:                 metricsserver.Options{BindAddress: cfg.Metrics.BindAddress},
+		HealthProbeBindAddress:  cfg.Health.BindAddress,
+		LeaderElection:          *le.LeaderElect,
+		LeaderElectionID:        le.ResourceName,
+		LeaderElectionNamespace: le.ResourceNamespace,
+		LeaseDuration:           &le.LeaseDuration.Duration,
+		RenewDeadline:           &le.RenewDeadline.Duration,
+		RetryPeriod:             &le.RetryPeriod.Duration,
+		Controller:              ctrlconfig.Controller{NeedLeaderElection: &needLeaderElection},
+	}
+	if len(cfg.WatchNamespaces) > 0 {
+		opts.Cache.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range cfg.WatchNamespaces {
+			opts.Cache.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,390 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	mgrcfgv1a1 "solo.io/sample-gateway-manager/api/config/v1alpha1"
+)
+
+const validConfig = `apiVersion: config.sample.io/v1alpha1
+kind: ManagerConfiguration
+controllerName: example.com/gateway-manager
+watchNamespaces:
+- gateways
+leaderElection:
+  leaderElect: true
+metrics:
+  bindAddress: "0"
+logging:
+  level: debug
+featureGates:
+  TCPRoute: true
+`
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: validConfig,
+		},
+		{
+			name:    "unknown field",
+			content: validConfig + "unknown: true\n",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported kind",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: Unknown\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid controller name",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\ncontrollerName: gateway-manager\n",
+			wantErr: true,
+		},
+		{
+			name: "renew deadline exceeds lease duration",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\n" +
+				"leaderElection:\n  leaseDuration: 5s\n  renewDeadline: 10s\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid bind address",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nhealth:\n  bindAddress: localhost\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid admin bind address",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nadmin:\n  bindAddress: localhost\n",
+			wantErr: true,
+		},
+		{
+			name:    "xds address without port",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nxds:\n  address: xds.example.com\n",
+			wantErr: true,
+		},
+		{
+			name:    "xds address with an ip",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nxds:\n  address: 10.0.0.1:18000\n",
+			wantErr: true,
+		},
+		{
+			name: "xds server secret overwriting the ca",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\n" +
+				"xds:\n  serverSecret:\n    name: sample-gateway-controller-xds-ca\n",
+			wantErr: true,
+		},
+		{
+			name: "xds certificate validity too short",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\n" +
+				"xds:\n  certificateValidity: 10m\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown feature gate",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nfeatureGates:\n  Unknown: true\n",
+			wantErr: true,
+		},
+		{
+			name: "multiple controllers",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\ncontrollers:\n" +
+				"- name: example.com/gateway-manager\n  defaultGatewayClassConfig:\n    namespace: default\n    name: internal\n" +
+				"- name: example.com/external\n",
+		},
+		{
+			name: "duplicate controllers",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\ncontrollers:\n" +
+				"- name: example.com/gateway-manager\n- name: example.com/gateway-manager\n",
+			wantErr: true,
+		},
+		{
+			name: "controllerName with different controllers",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\ncontrollerName: example.com/gateway-manager\n" +
+				"controllers:\n- name: example.com/external\n",
+			wantErr: true,
+		},
+		{
+			name: "invalid default gatewayclassconfig",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\ncontrollers:\n" +
+				"- name: example.com/gateway-manager\n  defaultGatewayClassConfig:\n    namespace: Default\n    name: internal\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid log format",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nlogging:\n  format: text\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid log level",
+			content: "apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\nlogging:\n  level: verbose\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, t.TempDir(), tc.content))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(cfg.Controllers) == 0 || cfg.Controllers[0].Name != "example.com/gateway-manager" {
+				t.Errorf("unexpected controllers %+v", cfg.Controllers)
+			}
+			if cfg.Health.BindAddress != DefaultHealthBindAddress {
+				t.Errorf("expected defaulted health bindAddress, found %q", cfg.Health.BindAddress)
+			}
+			if cfg.Logging.Format != LogFormatJSON || *cfg.Logging.Development {
+				t.Errorf("expected defaulted production JSON logging, found format %q and development %v",
+					cfg.Logging.Format, *cfg.Logging.Development)
+			}
+			if cfg.Admin.BindAddress != DefaultAdminBindAddress {
+				t.Errorf("expected defaulted admin bindAddress, found %q", cfg.Admin.BindAddress)
+			}
+			if cfg.XDS.Address != "" || cfg.XDS.CertificateValidity.Duration != DefaultCertValidity {
+				t.Errorf("expected no xds address and defaulted certificate validity, found %+v", cfg.XDS)
+			}
+			if cfg.XDS.ServerSecret.Namespace != cfg.XDS.CASecret.Namespace || cfg.XDS.ServerSecret.Name != DefaultXDSServerSecretName {
+				t.Errorf("expected defaulted xds server secret, found %+v", cfg.XDS.ServerSecret)
+			}
+		})
+	}
+}
+
+func TestSetControllerNames(t *testing.T) {
+	cfg := Default()
+	ref := &mgrcfgv1a1.GatewayClassConfigReference{Namespace: "default", Name: "internal"}
+	cfg.Controllers[0].DefaultGatewayClassConfig = ref
+
+	SetControllerNames(cfg, []string{DefaultControllerName, "example.com/external"})
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Controllers) != 2 || cfg.Controllers[1].Name != "example.com/external" {
+		t.Fatalf("unexpected controllers %+v", cfg.Controllers)
+	}
+	if cfg.Controllers[0].DefaultGatewayClassConfig != ref {
+		t.Error("expected the configuration of the existing controller to be kept")
+	}
+}
+
+func TestManagerOptions(t *testing.T) {
+	cfg, err := Load(writeConfig(t, t.TempDir(), validConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts := ManagerOptions(cfg)
+	if !opts.LeaderElection {
+		t.Error("expected leader election to be enabled")
+	}
+	// The controllers of standby replicas keep the object store warm.
+	if nle := opts.Controller.NeedLeaderElection; nle == nil || *nle {
+		t.Error("expected the controllers not to need leader election")
+	}
+	if _, ok := opts.Cache.DefaultNamespaces["gateways"]; !ok {
+		t.Errorf("expected the cache to watch the gateways namespace, found %v", opts.Cache.DefaultNamespaces)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	testCases := map[string]zapcore.Level{
+		"debug": zapcore.DebugLevel,
+		"info":  zapcore.InfoLevel,
+		"error": zapcore.ErrorLevel,
+		"3":     zapcore.Level(-3),
+	}
+	for level, want := range testCases {
+		got, err := ParseLogLevel(level)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", level, err)
+		}
+		if got != want {
+			t.Errorf("parsing %q: expected %v, found %v", level, want, got)
+		}
+	}
+	if _, err := ParseLogLevel("0"); err == nil {
+		t.Error("expected an error parsing verbosity 0")
+	}
+}
+
+func TestFormatLogLevel(t *testing.T) {
+	for _, level := range []string{"debug", "info", "error", "3"} {
+		l, err := ParseLogLevel(level)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", level, err)
+		}
+		if got := FormatLogLevel(l); got != level {
+			t.Errorf("expected %q, found %q", level, got)
+		}
+	}
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	w := NewWatcher("", Default(), level, logr.Discard())
+	handler := w.LogLevelHandler()
+
+	testCases := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantLevel  zapcore.Level
+	}{
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusOK, wantLevel: zapcore.InfoLevel},
+		{name: "set debug", method: http.MethodPut, body: `{"level": "debug"}`, wantStatus: http.StatusOK, wantLevel: zapcore.DebugLevel},
+		{name: "set verbosity", method: http.MethodPut, body: `{"level": "4"}`, wantStatus: http.StatusOK, wantLevel: zapcore.Level(-4)},
+		{name: "invalid level", method: http.MethodPut, body: `{"level": "verbose"}`, wantStatus: http.StatusBadRequest, wantLevel: zapcore.Level(-4)},
+		{name: "invalid body", method: http.MethodPut, body: `debug`, wantStatus: http.StatusBadRequest, wantLevel: zapcore.Level(-4)},
+		{name: "method not allowed", method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed, wantLevel: zapcore.Level(-4)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, "/debug/loglevel", strings.NewReader(tc.body)))
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, found %d: %s", tc.wantStatus, rec.Code, rec.Body)
+			}
+			if level.Level() != tc.wantLevel {
+				t.Errorf("expected log level %v, found %v", tc.wantLevel, level.Level())
+			}
+			if got, want := w.Current().Logging.Level, FormatLogLevel(tc.wantLevel); got != want {
+				t.Errorf("expected effective log level %q, found %q", want, got)
+			}
+		})
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), validConfig)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	level := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	w := NewWatcher(path, cfg, level, logr.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = w.Start(ctx)
+	}()
+
+	// Changing the level and the controller name only applies the level. The file
+	// is rewritten until the change is observed since the watch starts asynchronously.
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		writeConfig(t, filepath.Dir(path),
+			"apiVersion: config.sample.io/v1alpha1\nkind: ManagerConfiguration\n"+
+				"controllerName: example.com/other\nlogging:\n  level: error\n")
+		if level.Level() == zapcore.ErrorLevel {
+			break
+		}
+	}
+
+	if level.Level() != zapcore.ErrorLevel {
+		t.Fatalf("expected log level %v, found %v", zapcore.ErrorLevel, level.Level())
+	}
+	current := w.Current()
+	if current.Logging.Level != "error" {
+		t.Errorf("expected effective log level error, found %q", current.Logging.Level)
+	}
+	if current.ControllerName != "example.com/gateway-manager" {
+		t.Errorf("expected controllerName to require a restart, found %q", current.ControllerName)
+	}
+}
+
+func TestWatcherReloadKeepsRuntimeLevel(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, validConfig)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var restarts int
+	log := funcr.New(func(_, args string) {
+		if strings.Contains(args, "require a restart") {
+			restarts++
+		}
+	}, funcr.Options{})
+
+	level := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	w := NewWatcher(path, cfg, level, log)
+	if err := w.SetLogLevel("info"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A change to another field keeps the level set at runtime and is reported once.
+	writeConfig(t, dir, strings.Replace(validConfig, "example.com/gateway-manager", "example.com/other", 1))
+	w.reload()
+	w.reload()
+	if level.Level() != zapcore.InfoLevel {
+		t.Errorf("expected log level %v, found %v", zapcore.InfoLevel, level.Level())
+	}
+	if got := w.Current().Logging.Level; got != "info" {
+		t.Errorf("expected effective log level info, found %q", got)
+	}
+	if restarts != 1 {
+		t.Errorf("expected the restart to be reported once, found %d", restarts)
+	}
+
+	// A change to the level in the file applies it. Reverting the controllerName to
+	// the effective one needs no restart.
+	writeConfig(t, dir, strings.Replace(validConfig, "level: debug", "level: error", 1))
+	w.reload()
+	if level.Level() != zapcore.ErrorLevel {
+		t.Errorf("expected log level %v, found %v", zapcore.ErrorLevel, level.Level())
+	}
+	if restarts != 1 {
+		t.Errorf("expected the restart to be reported once, found %d", restarts)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+
+	mgrcfgv1a1 "solo.io/sample-gateway-manager/api/config/v1alpha1"
+)
+
+// Watcher holds the effective manager configuration. When started with the path of a
+// configuration file, it reloads the file on change and applies the fields that are
+// safe to change at runtime.
+type Watcher struct {
+	// Path is the path of the configuration file. The file is not watched if empty.
+	Path string
+	// Overrides is applied to every loaded configuration, e.g. to apply flags
+	// that take precedence over the file.
+	Overrides func(cfg *mgrcfgv1a1.ManagerConfiguration)
+	// LogLevel is the level of the manager logger, updated when the file changes.
+	LogLevel zap.AtomicLevel
+	Log      logr.Logger
+
+	mu      sync.RWMutex
+	current *mgrcfgv1a1.ManagerConfiguration
+	// loaded is the configuration last loaded from the file. Changes are detected
+	// against it rather than against current, whose level may be set at runtime and
+	// whose other fields keep their startup values.
+	loaded *mgrcfgv1a1.ManagerConfiguration
+}
+
+// NewWatcher returns a Watcher with cfg as the effective configuration.
+func NewWatcher(path string, cfg *mgrcfgv1a1.ManagerConfiguration, logLevel zap.AtomicLevel, log logr.Logger) *Watcher {
+	return &Watcher{
+		Path:     path,
+		LogLevel: logLevel,
+		Log:      log.WithName("config watcher"),
+		current:  cfg.DeepCopy(),
+		loaded:   cfg.DeepCopy(),
+	}
+}
+
+// Current returns a copy of the effective configuration.
+func (w *Watcher) Current() *mgrcfgv1a1.ManagerConfiguration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current.DeepCopy()
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable so the configuration
+// is reloaded by all replicas.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (w *Watcher) Start(ctx context.Context) error {
+	if w.Path == "" {
+		<-ctx.Done()
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the directory instead of the file since mounted ConfigMaps are updated
+	// by swapping a symlink.
+	if err := watcher.Add(filepath.Dir(w.Path)); err != nil {
+		return fmt.Errorf("failed to watch config file %s: %w", w.Path, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.Log.Error(err, "failed to watch config file", "path", w.Path)
+		}
+	}
+}
+
+// reload loads the configuration file and applies the fields that are safe to change
+// at runtime. Invalid files are ignored.
+func (w *Watcher) reload() {
+	cfg, err := Load(w.Path)
+	if err != nil {
+		w.Log.Error(err, "failed to reload config file; keeping the current configuration")
+		return
+	}
+	if w.Overrides != nil {
+		w.Overrides(cfg)
+	}
+	if err := Validate(cfg); err != nil {
+		w.Log.Error(err, "failed to reload config file; keeping the current configuration")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	loaded := w.loaded
+	if reflect.DeepEqual(cfg, loaded) {
+		return
+	}
+	w.loaded = cfg
+
+	if cfg.Logging.Level != loaded.Logging.Level {
+		// The level was validated by Load.
+		level, _ := ParseLogLevel(cfg.Logging.Level)
+		w.LogLevel.SetLevel(level)
+		w.current.Logging.Level = cfg.Logging.Level
+		w.Log.Info("updated log level", "level", cfg.Logging.Level)
+	}
+
+	// The remaining fields are only reported when they changed since the file was
+	// last loaded and differ from the effective configuration, so each change is
+	// reported once.
+	file, effective := cfg.DeepCopy(), w.current.DeepCopy()
+	file.Logging.Level = loaded.Logging.Level
+	effective.Logging.Level = loaded.Logging.Level
+	if !reflect.DeepEqual(file, loaded) && !reflect.DeepEqual(file, effective) {
+		w.Log.Info("config file changes require a restart of the manager to take effect", "path", w.Path)
+	}
+}
+
+// SetLogLevel sets the level of the manager logger until the level in the
+// configuration file changes.
+func (w *Watcher) SetLogLevel(level string) error {
+	l, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.LogLevel.SetLevel(l)
+	w.current.Logging.Level = FormatLogLevel(l)
+	w.Log.Info("updated log level", "level", w.current.Logging.Level)
+	return nil
+}
+
+// logLevel is the body of the log level endpoint.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// LogLevelHandler returns a handler serving the level of the manager logger as JSON
+// on GET, and setting it from a JSON body on PUT, e.g. {"level": "debug"}.
+func (w *Watcher) LogLevelHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body logLevel
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				http.Error(rw, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := w.SetLogLevel(body.Level); err != nil {
+				http.Error(rw, fmt.Sprintf("invalid level %q: %v", body.Level, err), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(rw).Encode(logLevel{Level: w.Current().Logging.Level}); err != nil {
+			w.Log.Error(err, "failed to encode log level")
+		}
+	})
+}
+
+// ServeHTTP serves the effective configuration as JSON.
+func (w *Watcher) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(w.Current()); err != nil {
+		w.Log.Error(err, "failed to encode config")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events records Kubernetes Events for state transitions of managed objects.
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// DefaultTTL is the default time an identical event is suppressed for.
+const DefaultTTL = 5 * time.Minute
+
+// Reasons of the events not named after a Gateway API condition reason.
+const (
+	// ReasonStatusUpdateFailed is the reason of the event recorded when the status
+	// of an object can't be updated.
+	ReasonStatusUpdateFailed = "StatusUpdateFailed"
+)
+
+// Recorder records events through an EventRecorder, dropping events identical to an
+// event recorded for the same object within the TTL so a hot reconcile loop doesn't
+// spam the event stream. A nil Recorder drops all events.
+type Recorder struct {
+	recorder record.EventRecorder
+	ttl      time.Duration
+	// now returns the current time, and is overridden by tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// recorded are the times events were last recorded.
+	recorded map[eventKey]time.Time
+}
+
+// eventKey identifies identical events.
+type eventKey struct {
+	uid, namespace, name       string
+	eventType, reason, message string
+}
+
+// NewRecorder returns a Recorder recording events through recorder and dropping
+// identical events within ttl.
+func NewRecorder(recorder record.EventRecorder, ttl time.Duration) *Recorder {
+	return &Recorder{
+		recorder: recorder,
+		ttl:      ttl,
+		now:      time.Now,
+		recorded: map[eventKey]time.Time{},
+	}
+}
+
+// Normal records an event of type Normal for obj.
+func (r *Recorder) Normal(obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	r.record(obj, corev1.EventTypeNormal, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// Warning records an event of type Warning for obj.
+func (r *Recorder) Warning(obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	r.record(obj, corev1.EventTypeWarning, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *Recorder) record(obj runtime.Object, eventType, reason, message string) {
+	if r == nil {
+		return
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	key := eventKey{
+		uid:       string(accessor.GetUID()),
+		namespace: accessor.GetNamespace(),
+		name:      accessor.GetName(),
+		eventType: eventType,
+		reason:    reason,
+		message:   message,
+	}
+
+	r.mu.Lock()
+	now := r.now()
+	if last, ok := r.recorded[key]; ok && now.Sub(last) < r.ttl {
+		r.mu.Unlock()
+		return
+	}
+	r.recorded[key] = now
+	r.prune(now)
+	r.mu.Unlock()
+
+	r.recorder.Event(obj, eventType, reason, message)
+}
+
+// prune removes the events recorded before the TTL. The recorder must be locked by
+// the caller.
+func (r *Recorder) prune(now time.Time) {
+	for key, last := range r.recorded {
+		if now.Sub(last) >= r.ttl {
+			delete(r.recorded, key)
+		}
+	}
+}
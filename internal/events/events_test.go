@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRecorder(t *testing.T) {
+	gw := &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", UID: "1"}}
+	other := &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other", UID: "2"}}
+
+	testCases := map[string]struct {
+		record func(r *Recorder, clock *time.Time)
+		want   []string
+	}{
+		"normal and warning": {
+			record: func(r *Recorder, _ *time.Time) {
+				r.Normal(gw, "Accepted", "gateway is accepted")
+				r.Warning(gw, "Invalid", "gateway is %s", "invalid")
+			},
+			want: []string{"Normal Accepted gateway is accepted", "Warning Invalid gateway is invalid"},
+		},
+		"duplicate within ttl": {
+			record: func(r *Recorder, clock *time.Time) {
+				r.Normal(gw, "Accepted", "gateway is accepted")
+				*clock = clock.Add(time.Minute)
+				r.Normal(gw, "Accepted", "gateway is accepted")
+			},
+			want: []string{"Normal Accepted gateway is accepted"},
+		},
+		"duplicate after ttl": {
+			record: func(r *Recorder, clock *time.Time) {
+				r.Normal(gw, "Accepted", "gateway is accepted")
+				*clock = clock.Add(DefaultTTL)
+				r.Normal(gw, "Accepted", "gateway is accepted")
+			},
+			want: []string{"Normal Accepted gateway is accepted", "Normal Accepted gateway is accepted"},
+		},
+		"different message": {
+			record: func(r *Recorder, _ *time.Time) {
+				r.Warning(gw, "Invalid", "listener %s is invalid", "http")
+				r.Warning(gw, "Invalid", "listener %s is invalid", "https")
+			},
+			want: []string{"Warning Invalid listener http is invalid", "Warning Invalid listener https is invalid"},
+		},
+		"different object": {
+			record: func(r *Recorder, _ *time.Time) {
+				r.Normal(gw, "Accepted", "gateway is accepted")
+				r.Normal(other, "Accepted", "gateway is accepted")
+			},
+			want: []string{"Normal Accepted gateway is accepted", "Normal Accepted gateway is accepted"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fake := record.NewFakeRecorder(10)
+			clock := time.Now()
+			r := NewRecorder(fake, DefaultTTL)
+			r.now = func() time.Time { return clock }
+
+			tc.record(r, &clock)
+			close(fake.Events)
+
+			var got []string
+			for e := range fake.Events {
+				got = append(got, e)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected events %v, found %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expected event %q, found %q", tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecorderPrune(t *testing.T) {
+	clock := time.Now()
+	r := NewRecorder(record.NewFakeRecorder(10), DefaultTTL)
+	r.now = func() time.Time { return clock }
+
+	r.Normal(&gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "old"}}, "Accepted", "gateway is accepted")
+	clock = clock.Add(DefaultTTL)
+	r.Normal(&gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "new"}}, "Accepted", "gateway is accepted")
+
+	if len(r.recorded) != 1 {
+		t.Errorf("expected 1 recorded event, found %d", len(r.recorded))
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	// A nil recorder drops events.
+	r.Normal(&gwapiv1.Gateway{}, "Accepted", "gateway is accepted")
+	r.Warning(&gwapiv1.Gateway{}, "Invalid", "gateway is invalid")
+}
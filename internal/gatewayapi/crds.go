@@ -0,0 +1,155 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewayapi
+
+import (
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// BundleVersionAnnotation is the annotation of Gateway API CRDs set to the
+	// version of the release bundle, e.g. v0.6.1.
+	BundleVersionAnnotation = "gateway.networking.k8s.io/bundle-version"
+	// ChannelAnnotation is the annotation of Gateway API CRDs set to the release
+	// channel, i.e. standard or experimental.
+	ChannelAnnotation = "gateway.networking.k8s.io/channel"
+	// MinBundleVersion is the oldest Gateway API release supported by the manager.
+	MinBundleVersion = "v0.6.0"
+)
+
+// SupportedVersions are the API versions supported by the manager for each Gateway
+// API kind, in order of preference.
+var SupportedVersions = map[gwapiv1.Kind][]string{
+	"GatewayClass":   {V1, V1Beta1},
+	"Gateway":        {V1, V1Beta1},
+	"HTTPRoute":      {V1, V1Beta1},
+	"ReferenceGrant": {V1Beta1},
+	"TCPRoute":       {"v1alpha2"},
+	"TLSRoute":       {"v1alpha2"},
+	"UDPRoute":       {"v1alpha2"},
+	"GRPCRoute":      {"v1alpha2"},
+}
+
+// CRD describes an installed Gateway API CustomResourceDefinition.
+type CRD struct {
+	// Name is the name of the CustomResourceDefinition, e.g. gateways.gateway.networking.k8s.io.
+	Name string       `json:"name"`
+	Kind gwapiv1.Kind `json:"kind"`
+	// ServedVersions are the API versions served for the kind.
+	ServedVersions []string `json:"servedVersions"`
+	// StorageVersion is the API version objects of the kind are persisted in.
+	StorageVersion string `json:"storageVersion"`
+	BundleVersion  string `json:"bundleVersion,omitempty"`
+	Channel        string `json:"channel,omitempty"`
+	// Established is true once the API server serves the kind.
+	Established bool `json:"established"`
+}
+
+// NewCRD returns the CRD described by crd, or false if crd is not a Gateway API CRD.
+func NewCRD(crd *apiextensionsv1.CustomResourceDefinition) (CRD, bool) {
+	if crd.Spec.Group != gwapiv1.GroupName {
+		return CRD{}, false
+	}
+
+	res := CRD{
+		Name:          crd.Name,
+		Kind:          gwapiv1.Kind(crd.Spec.Names.Kind),
+		BundleVersion: crd.Annotations[BundleVersionAnnotation],
+		Channel:       crd.Annotations[ChannelAnnotation],
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			res.ServedVersions = append(res.ServedVersions, v.Name)
+		}
+		if v.Storage {
+			res.StorageVersion = v.Name
+		}
+	}
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+			res.Established = true
+		}
+	}
+
+	return res, true
+}
+
+// APIVersion returns the API version used by the manager for the kind of c: the
+// storage version if supported, otherwise the preferred supported version served.
+// An empty string is returned if no supported version is served.
+func (c CRD) APIVersion() string {
+	if c.StorageVersion != "" && c.serves(c.StorageVersion) && supports(c.Kind, c.StorageVersion) {
+		return c.StorageVersion
+	}
+	for _, v := range SupportedVersions[c.Kind] {
+		if c.serves(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (c CRD) serves(version string) bool {
+	for _, v := range c.ServedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func supports(kind gwapiv1.Kind, version string) bool {
+	for _, v := range SupportedVersions[kind] {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Compatible returns an error if the manager can't reconcile the kind of c, i.e.
+// no supported API version is served or the bundle version is too old.
+func (c CRD) Compatible() error {
+	supported, ok := SupportedVersions[c.Kind]
+	if !ok {
+		return fmt.Errorf("unsupported kind %s", c.Kind)
+	}
+
+	if c.APIVersion() == "" {
+		return fmt.Errorf("CRD %s does not serve a supported version %v (served: %v)", c.Name, supported, c.ServedVersions)
+	}
+
+	// CRDs installed without the annotation, e.g. by an older or custom bundle, are
+	// accepted as long as a supported version is served.
+	if c.BundleVersion == "" {
+		return nil
+	}
+	v, err := version.ParseSemantic(c.BundleVersion)
+	if err != nil {
+		return fmt.Errorf("CRD %s has an invalid bundle version %q: %w", c.Name, c.BundleVersion, err)
+	}
+	if v.LessThan(version.MustParseSemantic(MinBundleVersion)) {
+		return fmt.Errorf("CRD %s has bundle version %s, the minimum supported version is %s",
+			c.Name, c.BundleVersion, MinBundleVersion)
+	}
+
+	return nil
+}
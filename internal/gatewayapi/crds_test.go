@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewayapi
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCRD(kind, bundleVersion string, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test.gateway.networking.k8s.io",
+			Annotations: map[string]string{BundleVersionAnnotation: bundleVersion, ChannelAnnotation: "experimental"},
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "gateway.networking.k8s.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: kind},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{{
+				Type:   apiextensionsv1.Established,
+				Status: apiextensionsv1.ConditionTrue,
+			}},
+		},
+	}
+	for _, v := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{Name: v, Served: true})
+	}
+	return crd
+}
+
+func TestNewCRD(t *testing.T) {
+	crd, ok := NewCRD(newCRD("Gateway", "v0.6.1", "v1alpha2", "v1beta1"))
+	if !ok {
+		t.Fatal("expected a Gateway API CRD")
+	}
+	if crd.Kind != "Gateway" || crd.BundleVersion != "v0.6.1" || crd.Channel != "experimental" || !crd.Established {
+		t.Errorf("unexpected CRD %+v", crd)
+	}
+
+	other := newCRD("Gateway", "v0.6.1", "v1beta1")
+	other.Spec.Group = "example.com"
+	if _, ok := NewCRD(other); ok {
+		t.Error("expected a CRD of another group to be ignored")
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	testCases := []struct {
+		name    string
+		crd     *apiextensionsv1.CustomResourceDefinition
+		wantErr bool
+	}{
+		{
+			name: "supported bundle version",
+			crd:  newCRD("Gateway", "v0.6.1", "v1alpha2", "v1beta1"),
+		},
+		{
+			name: "newer bundle version",
+			crd:  newCRD("Gateway", "v0.8.0", "v1beta1", "v1"),
+		},
+		{
+			name: "missing bundle version",
+			crd:  newCRD("TCPRoute", "", "v1alpha2"),
+		},
+		{
+			name:    "old bundle version",
+			crd:     newCRD("Gateway", "v0.5.1", "v1alpha2", "v1beta1"),
+			wantErr: true,
+		},
+		{
+			name:    "required version not served",
+			crd:     newCRD("Gateway", "v0.6.1", "v1alpha2"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid bundle version",
+			crd:     newCRD("Gateway", "latest", "v1beta1"),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported kind",
+			crd:     newCRD("BackendPolicy", "v0.6.1", "v1alpha2"),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			crd, _ := NewCRD(tc.crd)
+			err := crd.Compatible()
+			if tc.wantErr && err == nil {
+				t.Error("expected an error")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	testCases := []struct {
+		name string
+		crd  CRD
+		want string
+	}{
+		{
+			name: "storage version",
+			crd:  CRD{Kind: "Gateway", ServedVersions: []string{"v1", "v1beta1"}, StorageVersion: "v1beta1"},
+			want: V1Beta1,
+		},
+		{
+			name: "v1 storage version",
+			crd:  CRD{Kind: "GatewayClass", ServedVersions: []string{"v1", "v1beta1"}, StorageVersion: "v1"},
+			want: V1,
+		},
+		{
+			name: "unsupported storage version",
+			crd:  CRD{Kind: "Gateway", ServedVersions: []string{"v1alpha2", "v1beta1"}, StorageVersion: "v1alpha2"},
+			want: V1Beta1,
+		},
+		{
+			name: "no supported version served",
+			crd:  CRD{Kind: "Gateway", ServedVersions: []string{"v1alpha2"}, StorageVersion: "v1alpha2"},
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.crd.APIVersion(); got != tc.want {
+				t.Errorf("expected API version %q, found %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -17,9 +17,9 @@ limitations under the License.
 package gatewayapi
 
 import (
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
-func ObjectNameToStr(name gwapiv1b1.ObjectName) string {
+func ObjectNameToStr(name gwapiv1.ObjectName) string {
 	return string(name)
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewayapi
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// API versions of the GatewayClass and Gateway kinds supported by the manager.
+// Objects are handled as v1 objects internally and read and written using the
+// version served by the cluster.
+const (
+	V1      = "v1"
+	V1Beta1 = "v1beta1"
+)
+
+// NewGatewayClass returns an empty GatewayClass of the API version, defaulting to v1.
+func NewGatewayClass(version string) client.Object {
+	if version == V1Beta1 {
+		return &gwapiv1b1.GatewayClass{}
+	}
+	return &gwapiv1.GatewayClass{}
+}
+
+// ToV1GatewayClass returns obj as a v1 GatewayClass, or false if obj is not a
+// GatewayClass of a supported API version. The result shares its fields with obj.
+func ToV1GatewayClass(obj client.Object) (*gwapiv1.GatewayClass, bool) {
+	switch gc := obj.(type) {
+	case *gwapiv1.GatewayClass:
+		return gc, true
+	case *gwapiv1b1.GatewayClass:
+		return (*gwapiv1.GatewayClass)(gc), true
+	}
+	return nil, false
+}
+
+// FromV1GatewayClass returns gc as a GatewayClass of the API version, defaulting
+// to v1. The result shares its fields with gc.
+func FromV1GatewayClass(gc *gwapiv1.GatewayClass, version string) client.Object {
+	if version == V1Beta1 {
+		return (*gwapiv1b1.GatewayClass)(gc)
+	}
+	return gc
+}
+
+// NewGateway returns an empty Gateway of the API version, defaulting to v1.
+func NewGateway(version string) client.Object {
+	if version == V1Beta1 {
+		return &gwapiv1b1.Gateway{}
+	}
+	return &gwapiv1.Gateway{}
+}
+
+// ToV1Gateway returns obj as a v1 Gateway, or false if obj is not a Gateway of a
+// supported API version. The result shares its fields with obj.
+func ToV1Gateway(obj client.Object) (*gwapiv1.Gateway, bool) {
+	switch gw := obj.(type) {
+	case *gwapiv1.Gateway:
+		return gw, true
+	case *gwapiv1b1.Gateway:
+		return (*gwapiv1.Gateway)(gw), true
+	}
+	return nil, false
+}
+
+// FromV1Gateway returns gw as a Gateway of the API version, defaulting to v1. The
+// result shares its fields with gw.
+func FromV1Gateway(gw *gwapiv1.Gateway, version string) client.Object {
+	if version == V1Beta1 {
+		return (*gwapiv1b1.Gateway)(gw)
+	}
+	return gw
+}
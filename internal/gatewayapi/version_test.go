@@ -0,0 +1,74 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewayapi
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+func TestGatewayClassVersions(t *testing.T) {
+	gc := &gwapiv1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", ResourceVersion: "1"},
+		Spec:       gwapiv1.GatewayClassSpec{ControllerName: "example.com/gateway-manager"},
+	}
+
+	obj := FromV1GatewayClass(gc, V1Beta1)
+	v1beta1, ok := obj.(*gwapiv1b1.GatewayClass)
+	if !ok {
+		t.Fatalf("expected a v1beta1 GatewayClass, found %T", obj)
+	}
+	if v1beta1.ResourceVersion != "1" || v1beta1.Spec.ControllerName != gc.Spec.ControllerName {
+		t.Errorf("unexpected v1beta1 GatewayClass %+v", v1beta1)
+	}
+
+	back, ok := ToV1GatewayClass(obj)
+	if !ok || back != gc {
+		t.Error("expected the v1beta1 GatewayClass to convert back to the original v1 GatewayClass")
+	}
+
+	if _, ok := NewGatewayClass(V1Beta1).(*gwapiv1b1.GatewayClass); !ok {
+		t.Error("expected a new v1beta1 GatewayClass")
+	}
+	if _, ok := NewGatewayClass("").(*gwapiv1.GatewayClass); !ok {
+		t.Error("expected a new v1 GatewayClass by default")
+	}
+}
+
+func TestGatewayVersions(t *testing.T) {
+	gw := &gwapiv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+		Spec:       gwapiv1.GatewaySpec{GatewayClassName: "test"},
+	}
+
+	obj := FromV1Gateway(gw, V1Beta1)
+	if _, ok := obj.(*gwapiv1b1.Gateway); !ok {
+		t.Fatalf("expected a v1beta1 Gateway, found %T", obj)
+	}
+	if back, ok := ToV1Gateway(obj); !ok || back != gw {
+		t.Error("expected the v1beta1 Gateway to convert back to the original v1 Gateway")
+	}
+	if _, ok := ToV1Gateway(&gwapiv1.GatewayClass{}); ok {
+		t.Error("expected a GatewayClass not to convert to a Gateway")
+	}
+	if _, ok := FromV1Gateway(gw, V1).(*gwapiv1.Gateway); !ok {
+		t.Error("expected a v1 Gateway")
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/model"
+)
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+
+// CRDReconciler discovers the installed Gateway API CRDs and registers the controller
+// of each kind once its CRD is established and compatible, including CRDs installed
+// after the manager started.
+type CRDReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config *model.ManagerConfig
+	Log    logr.Logger
+
+	// Kinds are the Gateway API kinds reconciled by the manager.
+	Kinds []gwapiv1.Kind
+	// Setup registers the controller of the kind of crd with the manager, using the
+	// API version of crd. It is called at most once per kind.
+	Setup func(crd gatewayapi.CRD) error
+
+	ProcessorChan chan event.GenericEvent
+	ObjectStore   *ObjectStore
+
+	mu sync.Mutex
+	// setupVersions are the API versions of the registered controllers by kind.
+	setupVersions map[gwapiv1.Kind]string
+}
+
+func (r *CRDReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Log = reconcilerLogger(r.Log, mgr, "crd reconciler")
+	r.setupVersions = map[gwapiv1.Kind]string{}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiextensionsv1.CustomResourceDefinition{},
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+				return ok && crd.Spec.Group == gwapiv1.GroupName
+			})),
+		).
+		Complete(r)
+}
+
+func (r *CRDReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, log := requestLogger(ctx, r.Log, "CustomResourceDefinition", req)
+	log.V(logLevelDebug).Info("reconciling request")
+
+	obj := new(apiextensionsv1.CustomResourceDefinition)
+	if err := r.Client.Get(ctx, req.NamespacedName, obj); err != nil {
+		if errors.IsNotFound(err) {
+			r.removeCRD(req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	crd, ok := gatewayapi.NewCRD(obj)
+	if !ok || !r.reconciles(crd.Kind) {
+		return ctrl.Result{}, nil
+	}
+
+	r.ObjectStore.mu.Lock()
+	current, exists := r.ObjectStore.crds[crd.Kind]
+	changed := !exists || !reflect.DeepEqual(crd, current)
+	if changed {
+		r.ObjectStore.crds[crd.Kind] = crd
+	}
+	accepted := r.ObjectStore.gatewayclasses.accepted()
+	r.ObjectStore.mu.Unlock()
+
+	compatErr := crd.Compatible()
+	if changed {
+		log.Info("discovered Gateway API CRD", "kind", crd.Kind, "versions", crd.ServedVersions,
+			"storageVersion", crd.StorageVersion,
+			"bundleVersion", crd.BundleVersion, "channel", crd.Channel, "established", crd.Established)
+		if compatErr != nil {
+			log.Error(compatErr, "incompatible Gateway API CRD; not registering its controller", "kind", crd.Kind)
+		}
+		// Update the accepted gatewayclass statuses with the compatibility of the CRDs.
+		for i := range accepted {
+			r.ObjectStore.sendToProcessor(ctx, r.ProcessorChan, &accepted[i])
+		}
+	}
+
+	if !crd.Established || compatErr != nil {
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{}, r.setup(crd)
+}
+
+// reconciles returns true if kind is reconciled by the manager.
+func (r *CRDReconciler) reconciles(kind gwapiv1.Kind) bool {
+	for _, k := range r.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// setup registers the controller of the kind of crd if not registered yet.
+func (r *CRDReconciler) setup(crd gatewayapi.CRD) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Setup == nil {
+		return nil
+	}
+	if version, ok := r.setupVersions[crd.Kind]; ok {
+		if version != crd.APIVersion() {
+			r.Log.Info("API version of a registered controller changed; restart the manager to use it",
+				"name", crd.Kind, "current", version, "new", crd.APIVersion())
+		}
+		return nil
+	}
+	if err := r.Setup(crd); err != nil {
+		r.Log.Error(err, "unable to create controller", "name", crd.Kind)
+		return err
+	}
+	r.setupVersions[crd.Kind] = crd.APIVersion()
+	r.Log.Info("created controller", "name", crd.Kind, "apiVersion", crd.APIVersion())
+
+	return nil
+}
+
+// removeCRD removes the named CRD from the object store. Controllers can't be
+// removed from a running manager, so the controller of the kind keeps running
+// and retries listing its objects until the CRD is reinstalled.
+func (r *CRDReconciler) removeCRD(name string) {
+	r.ObjectStore.mu.Lock()
+	defer r.ObjectStore.mu.Unlock()
+
+	for kind, crd := range r.ObjectStore.crds {
+		if crd.Name == name {
+			r.Log.Info("Gateway API CRD removed", "kind", kind)
+			delete(r.ObjectStore.crds, kind)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+//go:build envtest
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+)
+
+var _ = Describe("CRD controller", func() {
+	It("records the installed CRDs of the reconciled kinds", func() {
+		Eventually(func() gatewayapi.CRD {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			return store.crds["TLSRoute"]
+		}, timeout, interval).Should(And(
+			HaveField("BundleVersion", "v1.0.0"),
+			HaveField("Channel", "experimental"),
+			HaveField("Established", true),
+		))
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		Expect(store.crds).NotTo(HaveKey(gwapiv1.Kind("UDPRoute")))
+	})
+
+	It("only reports incompatible gatewayclass and gateway CRDs", func() {
+		s := NewObjectStore()
+		s.crds["Gateway"] = gatewayapi.CRD{
+			Name: "gateways.gateway.networking.k8s.io", Kind: "Gateway",
+			ServedVersions: []string{"v1beta1", "v1"}, StorageVersion: "v1",
+		}
+		s.crds["TLSRoute"] = gatewayapi.CRD{
+			Name: "tlsroutes.gateway.networking.k8s.io", Kind: "TLSRoute",
+			ServedVersions: []string{"v1alpha2"}, StorageVersion: "v1alpha2", BundleVersion: "v0.5.1",
+		}
+		Expect(s.incompatibleCRDs()).To(Succeed())
+
+		s.crds["Gateway"] = gatewayapi.CRD{
+			Name: "gateways.gateway.networking.k8s.io", Kind: "Gateway",
+			ServedVersions: []string{"v1alpha2"}, StorageVersion: "v1alpha2",
+		}
+		Expect(s.incompatibleCRDs()).To(MatchError(ContainSubstring("gateways.gateway.networking.k8s.io")))
+	})
+
+	It("registers the controller of an installed CRD", func() {
+		Eventually(func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			_, ok := store.routeChans["TLSRoute"]
+			return ok
+		}, timeout, interval).Should(BeTrue())
+	})
+})
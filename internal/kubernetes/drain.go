@@ -0,0 +1,197 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/provisioner"
+	"solo.io/sample-gateway-manager/internal/status"
+	"solo.io/sample-gateway-manager/internal/utils/slice"
+)
+
+// gatewayFinalizer delays the deletion of a gateway, and of its infrastructure, until
+// its proxies are drained.
+const gatewayFinalizer = "gateway.sample.io/drain"
+
+// gatewayFinalizerWrite returns the update adding the drain finalizer to gw, or
+// removing it if add is false. The object store must be locked by the caller.
+func (p *Processor) gatewayFinalizerWrite(gw *gwapiv1.Gateway, add bool) finalizerWrite {
+	base := gw.DeepCopy()
+	gw = gw.DeepCopy()
+	if add {
+		gw.Finalizers = append(gw.Finalizers, gatewayFinalizer)
+	} else {
+		gw.Finalizers = slice.RemoveString(gw.Finalizers, gatewayFinalizer)
+	}
+
+	nsName := client.ObjectKeyFromObject(gw)
+	return finalizerWrite{
+		kind:   "gateway",
+		nsName: nsName,
+		obj:    p.gatewayObject(gw),
+		patch:  client.MergeFromWithOptions(p.gatewayObject(base), client.MergeFromWithOptimisticLock{}),
+		sync: func() {
+			// A gateway stored since the update was computed is newer.
+			if stored, ok := p.ObjectStore.gateways[nsName]; ok && stored.ResourceVersion == base.ResourceVersion {
+				p.ObjectStore.gateways[nsName] = *gw
+			}
+		},
+	}
+}
+
+// gatewayDrain is the drain of the proxies of a deleted gateway.
+type gatewayDrain struct {
+	nsName  types.NamespacedName
+	timeout time.Duration
+	// start is true if the drain starts, and stop is then the own proxy of the gateway
+	// to stop, or nil if the gateway is merged onto a shared proxy.
+	start bool
+	stop  *types.NamespacedName
+	// finalizer removes the drain finalizer of the gateway once the drain timeout
+	// elapsed, or is nil until then.
+	finalizer *finalizerWrite
+}
+
+// planDrain computes the drain of the proxies of gw, which is being deleted. The address
+// of the gateway is no longer advertised: its own Service is deleted, or its listeners
+// are removed from the shared proxy it was merged onto. Its own proxies are then scaled
+// down, and drain their listeners before they terminate. Once the drain timeout of the
+// gateway elapsed since its deletion, its finalizer is removed and its infrastructure
+// is garbage collected. It returns the drain and the time left until then, or nil if
+// the gateway isn't drained by this replica. Only the leader drains. The object store
+// must be locked by the caller.
+func (p *Processor) planDrain(ctx context.Context, gw *gwapiv1.Gateway) (*gatewayDrain, time.Duration) {
+	if !p.leading.Load() || !slice.ContainsString(gw.Finalizers, gatewayFinalizer) {
+		return nil, 0
+	}
+
+	// The configuration of the gateway may be invalid or gone by now.
+	timeout := cfgv1b1.DefaultProxyDrainTimeout
+	merged := false
+	if infra, err := p.gatewayInfra(gw); err == nil {
+		timeout = infra.Config.Deployment.DrainTimeout.Duration
+		merged = infra.Config.MergeGateways != nil
+	}
+
+	nsName := client.ObjectKeyFromObject(gw)
+	drain := &gatewayDrain{nsName: nsName, timeout: timeout, start: !p.draining[nsName]}
+	if drain.start {
+		p.updateDrainingGatewayStatus(ctx, gw)
+		if !merged {
+			key := types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}
+			drain.stop = &key
+		}
+	}
+
+	remaining := time.Until(gw.DeletionTimestamp.Add(timeout))
+	if remaining > 0 {
+		return drain, remaining
+	}
+	fw := p.gatewayFinalizerWrite(gw, false)
+	drain.finalizer = &fw
+	return drain, 0
+}
+
+// writeDrain starts the drain of the proxies of a deleted gateway, and removes its
+// finalizer once they are drained. The object store must not be locked by the caller.
+func (p *Processor) writeDrain(ctx context.Context, drain *gatewayDrain) error {
+	if drain.start {
+		if drain.stop != nil {
+			if err := p.stopProxy(ctx, *drain.stop); err != nil {
+				return err
+			}
+		}
+		logr.FromContextOrDiscard(ctx).Info("draining gateway proxies", "gateway", drain.nsName, "timeout", drain.timeout)
+		if p.draining == nil {
+			p.draining = map[types.NamespacedName]bool{}
+		}
+		p.draining[drain.nsName] = true
+	}
+
+	if drain.finalizer == nil {
+		return nil
+	}
+	if err := p.writeFinalizer(ctx, *drain.finalizer); err != nil {
+		return err
+	}
+	logr.FromContextOrDiscard(ctx).Info("drained gateway proxies", "gateway", drain.nsName)
+	delete(p.draining, drain.nsName)
+
+	return nil
+}
+
+// stopProxy stops advertising the address of the proxy named key and scales it down,
+// so its pods drain their listeners. The autoscaler of the proxy is deleted first so
+// it doesn't scale it back up. The object store must not be locked by the caller.
+func (p *Processor) stopProxy(ctx context.Context, key types.NamespacedName) error {
+	meta := metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}
+	for _, obj := range []client.Object{
+		&corev1.Service{ObjectMeta: meta},
+		&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: meta},
+	} {
+		if err := p.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %T %s: %w", obj, key, err)
+		}
+	}
+
+	scale := client.RawPatch(types.MergePatchType, []byte(`{"spec":{"replicas":0}}`))
+	if err := p.Patch(ctx, &appsv1.Deployment{ObjectMeta: meta}, scale); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to scale down deployment %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// updateDrainingGatewayStatus reports that gw is no longer programmed while its proxies
+// drain, and no longer lists its addresses. The object store must be locked by the
+// caller.
+func (p *Processor) updateDrainingGatewayStatus(ctx context.Context, gw *gwapiv1.Gateway) {
+	programmedCond := metav1.Condition{
+		Type:               string(gwapiv1.GatewayConditionProgrammed),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: gw.Generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             string(gwapiv1.GatewayReasonPending),
+		Message:            "gateway is being deleted; draining its proxies",
+	}
+
+	p.StatusUpdater.Send(ctx, status.Update{
+		Kind:           "Gateway",
+		NamespacedName: client.ObjectKeyFromObject(gw),
+		Resource:       gatewayapi.NewGateway(p.ObjectStore.apiVersion("Gateway")),
+		Mutate: func(obj client.Object) {
+			// The object is of a supported version.
+			gw, _ := gatewayapi.ToV1Gateway(obj)
+			gw.Status.Conditions = status.MergeConditions(gw.Status.Conditions, programmedCond)
+			gw.Status.Addresses = nil
+		},
+	})
+}
@@ -19,7 +19,9 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -33,11 +35,11 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/tracing"
 )
 
 //+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch;create;update;patch;delete
@@ -56,16 +58,22 @@ type GatewayReconciler struct {
 	Scheme *runtime.Scheme
 	Config *model.ManagerConfig
 	Log    logr.Logger
+	// APIVersion is the API version used to watch gateways, defaulting to v1.
+	APIVersion string
 
 	ProcessorChan chan event.GenericEvent
 	ObjectStore   *ObjectStore
 }
 
 func (r *GatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.Log = log.FromContext(context.Background()).WithName("gateway reconciler")
+	r.Log = reconcilerLogger(r.Log, mgr, "gateway reconciler")
+
+	r.ObjectStore.mu.Lock()
+	r.ObjectStore.apiVersions["Gateway"] = r.APIVersion
+	r.ObjectStore.mu.Unlock()
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&gwapiv1b1.Gateway{},
+		For(gatewayapi.NewGateway(r.APIVersion),
 			builder.WithPredicates(predicate.NewPredicateFuncs(r.gatewayHasMatchingGatewayClass)),
 		).
 		/*Watches(
@@ -73,65 +81,102 @@ func (r *GatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(mapServiceToGateway),
 		).
 		Watches(
-			&source.Kind{Type: &gwapiv1b1.GatewayClass{}},
+			&source.Kind{Type: &gwapiv1.GatewayClass{}},
 			handler.EnqueueRequestsFromMapFunc(r.mapGatewayClassToGateway),
 		).*/
 		Complete(r)
 }
 
 func (r *GatewayReconciler) gatewayHasMatchingGatewayClass(obj client.Object) bool {
-	gw, ok := obj.(*gwapiv1b1.Gateway)
+	gw, ok := gatewayapi.ToV1Gateway(obj)
 	if !ok {
 		r.Log.Error(fmt.Errorf("unexpected object type"), "expected", "Gateway", "found", obj.GetObjectKind())
 		return false
 	}
 
-	gc := &gwapiv1b1.GatewayClass{}
-	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: string(gw.Spec.GatewayClassName)}, gc); err != nil {
+	gc, err := r.getGatewayClass(context.Background(), string(gw.Spec.GatewayClassName))
+	if err != nil {
 		r.Log.Error(err, "failed to get gatewayclass", "name", gw.Spec.GatewayClassName)
 		return false
 	}
 
-	return string(gc.Spec.ControllerName) == r.Config.ControllerName
+	return r.Config.Manages(gc)
+}
+
+// getGatewayClass gets the named gatewayclass using the API version of the
+// gatewayclass reconciler.
+func (r *GatewayReconciler) getGatewayClass(ctx context.Context, name string) (*gwapiv1.GatewayClass, error) {
+	r.ObjectStore.mu.Lock()
+	version := r.ObjectStore.apiVersion("GatewayClass")
+	r.ObjectStore.mu.Unlock()
+
+	obj := gatewayapi.NewGatewayClass(version)
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, obj); err != nil {
+		return nil, err
+	}
+	// The object is of a supported version.
+	gc, _ := gatewayapi.ToV1GatewayClass(obj)
+
+	return gc, nil
 }
 
 func (r *GatewayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	r.Log.Info("reconciling request", "namespace", req.Namespace, "name", req.Name)
+	ctx, span := tracing.Tracer().Start(ctx, "GatewayReconciler.Reconcile",
+		trace.WithAttributes(tracing.AttrKind.String("Gateway"),
+			tracing.AttrNamespace.String(req.Namespace), tracing.AttrName.String(req.Name)))
+	defer span.End()
+
+	ctx, log := requestLogger(ctx, r.Log, "Gateway", req)
+	log.V(logLevelDebug).Info("reconciling request")
 
-	gw := new(gwapiv1b1.Gateway)
-	if err := r.Client.Get(ctx, req.NamespacedName, gw); err != nil {
+	obj := gatewayapi.NewGateway(r.APIVersion)
+	if err := r.Client.Get(ctx, req.NamespacedName, obj); err != nil {
 		if errors.IsNotFound(err) {
-			r.Log.Info("reconciled object no longer exists")
+			log.Info("reconciled object no longer exists")
+			r.removeGateway(ctx, req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
-		return ctrl.Result{}, err
+		return ctrl.Result{}, tracing.RecordError(span, err)
 	}
+	// The object is of a supported version.
+	gw, _ := gatewayapi.ToV1Gateway(obj)
+	span.SetAttributes(tracing.AttrGeneration.Int64(gw.Generation))
+	ctx, log = withGeneration(ctx, gw)
 
 	// Process the gatewayclass if it doesn't exist or differs from the internal store.
-	gc, ok := r.ObjectStore.gatewayclasses.matched[gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName)]
+	r.ObjectStore.mu.Lock()
+	gc, ok := r.ObjectStore.gatewayclasses.get(gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName))
+	r.ObjectStore.mu.Unlock()
 	if !ok {
-		gc = gwapiv1b1.GatewayClass{}
-		if err := r.Client.Get(ctx, types.NamespacedName{Name: string(gw.Spec.GatewayClassName)}, &gc); err != nil {
+		current, err := r.getGatewayClass(ctx, string(gw.Spec.GatewayClassName))
+		if err != nil {
 			if errors.IsNotFound(err) {
 				return ctrl.Result{}, nil
 			}
-			return ctrl.Result{}, err
+			return ctrl.Result{}, tracing.RecordError(span, err)
 		}
+		gc = *current
 	}
 
-	if string(gc.Spec.ControllerName) != r.Config.ControllerName {
+	if !r.Config.Manages(&gc) {
 		return ctrl.Result{}, nil
 	}
 
-	current, ok := r.ObjectStore.gateways[req.NamespacedName]
-
 	// Process the gateway if it doesn't exist or differs from the internal store.
-	if !ok || !reflect.DeepEqual(gw, current) {
-		r.ObjectStore.mu.Lock()
-		defer r.ObjectStore.mu.Unlock()
+	r.ObjectStore.mu.Lock()
+	current, ok := r.ObjectStore.gateways[req.NamespacedName]
+	changed := !ok || !reflect.DeepEqual(*gw, current)
+	if changed {
 		r.ObjectStore.gateways[req.NamespacedName] = *gw
-		update := event.GenericEvent{Object: gw}
-		r.ProcessorChan <- update
+	}
+	r.ObjectStore.mu.Unlock()
+
+	if changed {
+		log.Info("gateway changed")
+		r.ObjectStore.sendToProcessor(ctx, r.ProcessorChan, gw)
+	}
+	if !ok {
+		r.ObjectStore.sendGatewayToRouteReconcilers(ctx, gw)
 	}
 
 	/*gatewayReadyStatus, gatewayReadyStatusIsSet := isGatewayReady(gw)
@@ -206,7 +251,27 @@ func (r *GatewayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	updateGatewayStatus(ctx, gw, svc)
 	factorizeStatus(gw, oldGateway)*/
 
-	r.Log.Info("reconciled request", "namespace", req.Namespace, "name", req.Name)
+	log.V(logLevelDebug).Info("reconciled request")
 
 	return ctrl.Result{}, nil
 }
+
+// removeGateway removes the gateway from the object store and notifies the processor
+// of the gateway's gatewayclass so its finalizer can be updated, and the route
+// reconcilers so they remove the routes attached to it.
+func (r *GatewayReconciler) removeGateway(ctx context.Context, nsName types.NamespacedName) {
+	r.ObjectStore.mu.Lock()
+	gw, ok := r.ObjectStore.gateways[nsName]
+	if ok {
+		delete(r.ObjectStore.gateways, nsName)
+	}
+	r.ObjectStore.mu.Unlock()
+
+	if ok {
+		gc := &gwapiv1.GatewayClass{
+			ObjectMeta: metav1.ObjectMeta{Name: gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName)},
+		}
+		r.ObjectStore.sendToProcessor(ctx, r.ProcessorChan, gc)
+		r.ObjectStore.sendGatewayToRouteReconcilers(ctx, &gw)
+	}
+}
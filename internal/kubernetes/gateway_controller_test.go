@@ -0,0 +1,632 @@
+//go:build envtest
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/provisioner"
+	"solo.io/sample-gateway-manager/internal/tracing"
+)
+
+var _ = Describe("Gateway controller", func() {
+	ctx := context.Background()
+
+	var gc *gwapiv1.GatewayClass
+	var gcc *cfgv1b1.GatewayClassConfig
+
+	BeforeEach(func() {
+		// The proxies of deleted gateways don't drain unless a spec configures it.
+		gcc = &cfgv1b1.GatewayClassConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway-test"},
+			Spec: cfgv1b1.GatewayClassConfigSpec{
+				Deployment: &cfgv1b1.ProxyDeployment{DrainTimeout: &metav1.Duration{}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, gcc)).To(Succeed())
+
+		gc = newGatewayClass("gateway-test", testControllerName)
+		gc.Spec.ParametersRef = gatewayClassConfigRef(gcc)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, gc.Name)
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+	})
+
+	AfterEach(func() {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, gc))).To(Succeed())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(gc), gc)
+			return apierrors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, gcc))).To(Succeed())
+	})
+
+	It("accepts a gateway of the accepted gatewayclass", func() {
+		gw := newGateway("default", "accepted", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayAcceptedStatus(ctx, client.ObjectKeyFromObject(gw))
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("traces the gateway reconcile to the processor", func() {
+		gw := newGateway("default", "traced", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		var reconcile sdktrace.ReadOnlySpan
+		Eventually(func() bool {
+			reconcile = endedSpan("GatewayReconciler.Reconcile", gw.Name)
+			return reconcile != nil
+		}, timeout, interval).Should(BeTrue())
+		Expect(reconcile.Attributes()).To(ContainElement(tracing.AttrGeneration.Int64(1)))
+
+		By("linking the processor span to a gateway reconcile span")
+		Eventually(func() bool {
+			linked := map[trace.SpanID]bool{}
+			for _, span := range spanRecorder.Ended() {
+				if span.Name() == "Processor.Reconcile" {
+					for _, link := range span.Links() {
+						linked[link.SpanContext.SpanID()] = true
+					}
+				}
+			}
+			for _, span := range spanRecorder.Ended() {
+				if span.Name() == "GatewayReconciler.Reconcile" && hasNameAttribute(span, gw.Name) &&
+					linked[span.SpanContext().SpanID()] {
+					return true
+				}
+			}
+			return false
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("includes an accepted gateway in the store snapshot", func() {
+		gw := newGateway("default", "snapshot", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() []types.NamespacedName {
+			var keys []types.NamespacedName
+			snap := store.Snapshot()
+			for i := range snap.Gateways {
+				keys = append(keys, client.ObjectKeyFromObject(&snap.Gateways[i]))
+			}
+			return keys
+		}, timeout, interval).Should(ContainElement(client.ObjectKeyFromObject(gw)))
+		Expect(store.Snapshot().GatewayClasses).To(ContainElement(HaveField("Name", gc.Name)))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("manages the gatewayclass finalizer", func() {
+		gw := newGateway("default", "finalizer", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() []string {
+			return gatewayClassFinalizers(ctx, gc.Name)
+		}, timeout, interval).Should(ContainElement(gatewayClassFinalizer))
+
+		By("deleting the gateway")
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+
+		Eventually(func() []string {
+			return gatewayClassFinalizers(ctx, gc.Name)
+		}, timeout, interval).ShouldNot(ContainElement(gatewayClassFinalizer))
+	})
+
+	It("doesn't update the gatewayclass finalizer on a standby replica", func() {
+		standby := &Processor{Client: k8sClient, ObjectStore: NewObjectStore()}
+		gw := &gwapiv1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "standby"},
+			Spec:       gwapiv1.GatewaySpec{GatewayClassName: gwapiv1.ObjectName(gc.Name)},
+		}
+		standby.ObjectStore.gateways[client.ObjectKeyFromObject(gw)] = *gw
+
+		current := new(gwapiv1.GatewayClass)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(gc), current)).To(Succeed())
+		standby.ObjectStore.gatewayclasses.add(current)
+		fws := standby.gatewayClassFinalizerWrites(ctx)
+		Expect(fws).To(BeEmpty())
+		Expect(standby.writeGatewayClasses(ctx, &writes{gatewayClassFinalizers: fws})).To(Succeed())
+		Consistently(func() []string {
+			return gatewayClassFinalizers(ctx, gc.Name)
+		}, time.Second, interval).ShouldNot(ContainElement(gatewayClassFinalizer))
+	})
+
+	It("blocks gatewayclass deletion until its gateways are deleted", func() {
+		gw := newGateway("default", "blocking", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() []string {
+			return gatewayClassFinalizers(ctx, gc.Name)
+		}, timeout, interval).Should(ContainElement(gatewayClassFinalizer))
+
+		By("deleting the gatewayclass")
+		Expect(k8sClient.Delete(ctx, gc)).To(Succeed())
+		Consistently(func() error {
+			return k8sClient.Get(ctx, client.ObjectKeyFromObject(gc), new(gwapiv1.GatewayClass))
+		}, 2*time.Second, interval).Should(Succeed())
+
+		By("deleting the gateway")
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(gc), new(gwapiv1.GatewayClass))
+			return apierrors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("provisions the proxy with the overrides of the gateway", func() {
+		replicas := int32(2)
+		lbClass := "example.com/internal"
+		gwc := &cfgv1b1.GatewayConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "overrides"},
+			Spec: cfgv1b1.GatewayConfigSpec{
+				Deployment: &cfgv1b1.ProxyDeployment{Replicas: &replicas},
+				Service: &cfgv1b1.ProxyService{
+					Annotations:       map[string]string{"example.com/config": "gatewayconfig"},
+					LoadBalancerClass: &lbClass,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, gwc)).To(Succeed())
+
+		gw := newGateway("default", "overrides", gc.Name)
+		gw.Annotations = map[string]string{
+			provisioner.AnnotationParameters: gwc.Name,
+			provisioner.AnnotationReplicas:   "3",
+		}
+		gw.Spec.Infrastructure = &gwapiv1.GatewayInfrastructure{
+			Annotations: map[gwapiv1.AnnotationKey]gwapiv1.AnnotationValue{"example.com/team": "edge"},
+		}
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		key := types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}
+		Eventually(func() *int32 {
+			deploy := new(appsv1.Deployment)
+			if err := k8sClient.Get(ctx, key, deploy); err != nil {
+				return nil
+			}
+			return deploy.Spec.Replicas
+		}, timeout, interval).Should(HaveValue(Equal(int32(3))))
+
+		svc := new(corev1.Service)
+		Expect(k8sClient.Get(ctx, key, svc)).To(Succeed())
+		Expect(svc.Annotations).To(HaveKeyWithValue("example.com/config", "gatewayconfig"))
+		Expect(svc.Annotations).To(HaveKeyWithValue("example.com/team", "edge"))
+		Expect(svc.Spec.LoadBalancerClass).To(HaveValue(Equal(lbClass)))
+		Expect(svc.OwnerReferences).To(ContainElement(HaveField("Name", gw.Name)))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, gwc)).To(Succeed())
+	})
+
+	It("provisions an autoscaler and a disruption budget for the proxy", func() {
+		maxUnavailable := intstr.FromString("25%")
+		gwc := &cfgv1b1.GatewayConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "autoscaled"},
+			Spec: cfgv1b1.GatewayConfigSpec{
+				Deployment: &cfgv1b1.ProxyDeployment{
+					Autoscaling:         &cfgv1b1.ProxyAutoscaling{MaxReplicas: 4},
+					PodDisruptionBudget: &cfgv1b1.ProxyPodDisruptionBudget{MaxUnavailable: &maxUnavailable},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, gwc)).To(Succeed())
+
+		gw := newGateway("default", "autoscaled", gc.Name)
+		gw.Annotations = map[string]string{provisioner.AnnotationParameters: gwc.Name}
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		key := types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}
+		hpa := new(autoscalingv2.HorizontalPodAutoscaler)
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, hpa)
+		}, timeout, interval).Should(Succeed())
+		Expect(hpa.Spec.MaxReplicas).To(Equal(int32(4)))
+		Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal(key.Name))
+		Expect(hpa.OwnerReferences).To(ContainElement(HaveField("Name", gw.Name)))
+
+		pdb := new(policyv1.PodDisruptionBudget)
+		Expect(k8sClient.Get(ctx, key, pdb)).To(Succeed())
+		Expect(pdb.Spec.MaxUnavailable).To(HaveValue(Equal(maxUnavailable)))
+
+		By("deleting the autoscaler once autoscaling is disabled")
+		base := gwc.DeepCopy()
+		gwc.Spec.Deployment.Autoscaling = nil
+		Expect(k8sClient.Patch(ctx, gwc, client.MergeFrom(base))).To(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, key, new(autoscalingv2.HorizontalPodAutoscaler)))
+		}, timeout, interval).Should(BeTrue())
+		Expect(k8sClient.Get(ctx, key, new(policyv1.PodDisruptionBudget))).To(Succeed())
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, gwc)).To(Succeed())
+	})
+
+	It("bootstraps the proxy with an xDS certificate issued by the CA", func() {
+		gw := newGateway("default", "bootstrapped", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		key := types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}
+		secret := new(corev1.Secret)
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, secret)
+		}, timeout, interval).Should(Succeed())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(secret.Data).To(HaveKeyWithValue("ca.crt", xdsCA.CertPEM()))
+		_, err := xdsCA.Verify(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey],
+			provisioner.NodeID(key), time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		cm := new(corev1.ConfigMap)
+		Expect(k8sClient.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("envoy.yaml", ContainSubstring("id: "+provisioner.NodeID(key))))
+		Expect(cm.Data["envoy.yaml"]).To(ContainSubstring("address: xds.sample-gateway-manager.svc"))
+		Expect(cm.OwnerReferences).To(ContainElement(HaveField("Name", gw.Name)))
+
+		deploy := new(appsv1.Deployment)
+		Expect(k8sClient.Get(ctx, key, deploy)).To(Succeed())
+		Expect(deploy.Spec.Template.Annotations).To(HaveKey(provisioner.AnnotationBootstrapHash))
+		volumes := deploy.Spec.Template.Spec.Volumes
+		Expect(volumes).To(HaveLen(2))
+		Expect(volumes[0].ConfigMap).To(HaveField("Name", key.Name))
+		Expect(volumes[1].Secret).To(HaveField("SecretName", key.Name))
+
+		By("serving the certificate and the provisioned resources on the debug endpoints")
+		Expect(processor.Certificates()).To(ContainElement(And(
+			HaveField("Proxy", key),
+			HaveField("NodeID", provisioner.NodeID(key)),
+			HaveField("NotAfter", BeTemporally(">", time.Now())),
+		)))
+		Expect(processor.Infrastructure()).To(ContainElement(And(
+			HaveField("Gateway", client.ObjectKeyFromObject(gw)),
+			HaveField("Proxy", key),
+			HaveField("Resources", ContainElements("Deployment "+key.String(), "Secret "+key.String())),
+		)))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("issues the xDS server certificate into a Secret", func() {
+		issuer := &ServerCertificateIssuer{
+			Client:   k8sClient,
+			Reader:   k8sClient,
+			Log:      logr.Discard(),
+			CA:       xdsCA,
+			Secret:   types.NamespacedName{Namespace: "default", Name: "xds-server"},
+			Host:     "xds.sample-gateway-manager.svc",
+			Validity: time.Hour,
+		}
+		now := time.Now()
+		renewAt, err := issuer.ensure(ctx, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret := new(corev1.Secret)
+		Expect(k8sClient.Get(ctx, issuer.Secret, secret)).To(Succeed())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(secret.Data).To(HaveKeyWithValue("ca.crt", xdsCA.CertPEM()))
+		cert, err := xdsCA.VerifyServer(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], issuer.Host, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert.RenewAt()).To(Equal(renewAt))
+
+		By("keeping the certificate until it is due for renewal")
+		Expect(issuer.ensure(ctx, now.Add(time.Minute))).To(Equal(renewAt))
+
+		By("renewing the certificate once it is due")
+		Expect(issuer.ensure(ctx, renewAt)).To(BeTemporally(">", renewAt))
+
+		Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+	})
+
+	It("rejects a gateway with a malformed override", func() {
+		gw := newGateway("default", "malformed-override", gc.Name)
+		gw.Annotations = map[string]string{provisioner.AnnotationReplicas: "many"}
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayAcceptedStatus(ctx, client.ObjectKeyFromObject(gw))
+		}, timeout, interval).Should(Equal(metav1.ConditionFalse))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("requests the IP address of the gateway from the load balancer", func() {
+		gw := newGateway("default", "static-ip", gc.Name)
+		gw.Spec.Addresses = []gwapiv1.GatewayAddress{{Value: "192.0.2.10"}}
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		key := types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}
+		Eventually(func() string {
+			svc := new(corev1.Service)
+			if err := k8sClient.Get(ctx, key, svc); err != nil {
+				return ""
+			}
+			return svc.Spec.LoadBalancerIP
+		}, timeout, interval).Should(Equal("192.0.2.10"))
+
+		By("waiting for the load balancer to assign the address")
+		Eventually(func() string {
+			cond := gatewayCondition(ctx, client.ObjectKeyFromObject(gw), string(gwapiv1.GatewayConditionProgrammed))
+			if cond == nil {
+				return ""
+			}
+			return cond.Reason
+		}, timeout, interval).Should(Equal(string(gwapiv1.GatewayReasonAddressNotAssigned)))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("rejects an unsupported address type", func() {
+		gw := newGateway("default", "named-address", gc.Name)
+		named := gwapiv1.NamedAddressType
+		gw.Spec.Addresses = []gwapiv1.GatewayAddress{{Type: &named, Value: "reserved"}}
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() string {
+			cond := gatewayCondition(ctx, client.ObjectKeyFromObject(gw), string(gwapiv1.GatewayConditionAccepted))
+			if cond == nil {
+				return ""
+			}
+			return cond.Reason
+		}, timeout, interval).Should(Equal(string(gwapiv1.GatewayReasonUnsupportedAddress)))
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+	})
+
+	It("merges the gateways of the gatewayclass onto a shared proxy", func() {
+		scope := cfgv1b1.MergeGatewaysNamespace
+		merged := &cfgv1b1.GatewayClassConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "merged"},
+			Spec: cfgv1b1.GatewayClassConfigSpec{
+				MergeGateways: &scope,
+				Deployment:    &cfgv1b1.ProxyDeployment{DrainTimeout: &metav1.Duration{}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, merged)).To(Succeed())
+		base := gc.DeepCopy()
+		gc.Spec.ParametersRef = gatewayClassConfigRef(merged)
+		Expect(k8sClient.Patch(ctx, gc, client.MergeFrom(base))).To(Succeed())
+
+		first := newGateway("default", "merged-a", gc.Name)
+		Expect(k8sClient.Create(ctx, first)).To(Succeed())
+		second := newGateway("default", "merged-b", gc.Name)
+		second.Spec.Listeners[0].Port = 8080
+		Expect(k8sClient.Create(ctx, second)).To(Succeed())
+		conflicting := newGateway("default", "merged-c", gc.Name)
+		Expect(k8sClient.Create(ctx, conflicting)).To(Succeed())
+
+		key := types.NamespacedName{Namespace: "default", Name: provisioner.MergedResourceName(gc.Name)}
+		Eventually(func() []int32 {
+			svc := new(corev1.Service)
+			if err := k8sClient.Get(ctx, key, svc); err != nil {
+				return nil
+			}
+			var ports []int32
+			for _, p := range svc.Spec.Ports {
+				ports = append(ports, p.Port)
+			}
+			return ports
+		}, timeout, interval).Should(Equal([]int32{80, 8080}))
+		Expect(k8sClient.Get(ctx, key, new(appsv1.Deployment))).To(Succeed())
+
+		perGateway := types.NamespacedName{Namespace: "default", Name: provisioner.ResourceName(first.Name)}
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, perGateway, new(corev1.Service)))).To(BeTrue())
+
+		Eventually(func() string {
+			cond := gatewayCondition(ctx, client.ObjectKeyFromObject(conflicting), string(gwapiv1.GatewayConditionAccepted))
+			if cond == nil {
+				return ""
+			}
+			return cond.Reason
+		}, timeout, interval).Should(Equal(string(gwapiv1.GatewayReasonListenersNotValid)))
+		Expect(gatewayAcceptedStatus(ctx, client.ObjectKeyFromObject(first))).To(Equal(metav1.ConditionTrue))
+
+		Expect(k8sClient.Delete(ctx, first)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, second)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, conflicting)).To(Succeed())
+
+		By("deleting the shared proxy once its gateways are deleted")
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, key, new(appsv1.Deployment)))
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, merged)).To(Succeed())
+	})
+
+	It("drains the proxy of a deleted gateway before deleting it", func() {
+		gwc := &cfgv1b1.GatewayConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "draining"},
+			Spec: cfgv1b1.GatewayConfigSpec{
+				Deployment: &cfgv1b1.ProxyDeployment{DrainTimeout: &metav1.Duration{Duration: 3 * time.Second}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, gwc)).To(Succeed())
+
+		gw := newGateway("default", "draining", gc.Name)
+		gw.Annotations = map[string]string{provisioner.AnnotationParameters: gwc.Name}
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+
+		Eventually(func() []string {
+			current := new(gwapiv1.Gateway)
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(gw), current); err != nil {
+				return nil
+			}
+			return current.Finalizers
+		}, timeout, interval).Should(ContainElement(gatewayFinalizer))
+
+		key := types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}
+		deploy := new(appsv1.Deployment)
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, deploy)
+		}, timeout, interval).Should(Succeed())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(
+			ContainElement(And(ContainSubstring("POST /drain_listeners?graceful"), HaveSuffix("sleep 3"))))
+
+		By("deleting the gateway")
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, key, new(corev1.Service)))
+		}, timeout, interval).Should(BeTrue())
+		Eventually(func() *int32 {
+			if err := k8sClient.Get(ctx, key, deploy); err != nil {
+				return nil
+			}
+			return deploy.Spec.Replicas
+		}, timeout, interval).Should(HaveValue(BeZero()))
+		Eventually(func() string {
+			cond := gatewayCondition(ctx, client.ObjectKeyFromObject(gw), string(gwapiv1.GatewayConditionProgrammed))
+			if cond == nil {
+				return ""
+			}
+			return cond.Reason
+		}, timeout, interval).Should(Equal(string(gwapiv1.GatewayReasonPending)))
+
+		By("removing the finalizer once the proxy is drained")
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(gw), new(gwapiv1.Gateway)))
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, gwc)).To(Succeed())
+	})
+
+	It("removes a deleted gateway from the object store", func() {
+		gw := newGateway("default", "deleted", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+		key := client.ObjectKeyFromObject(gw)
+
+		Eventually(func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			_, ok := store.gateways[key]
+			return ok
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+
+		Eventually(func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			_, ok := store.gateways[key]
+			return ok
+		}, timeout, interval).Should(BeFalse())
+	})
+})
+
+// gatewayClassConfigRef returns a parametersRef of a gatewayclass to gcc.
+func gatewayClassConfigRef(gcc *cfgv1b1.GatewayClassConfig) *gwapiv1.ParametersReference {
+	return &gwapiv1.ParametersReference{
+		Group:     gwapiv1.Group(cfgv1b1.GroupVersion.Group),
+		Kind:      gwapiv1.Kind(gatewayClassConfigKind),
+		Name:      gcc.Name,
+		Namespace: (*gwapiv1.Namespace)(&gcc.Namespace),
+	}
+}
+
+func newGateway(namespace, name, className string) *gwapiv1.Gateway {
+	return &gwapiv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: gwapiv1.GatewaySpec{
+			GatewayClassName: gwapiv1.ObjectName(className),
+			Listeners: []gwapiv1.Listener{{
+				Name:     "http",
+				Protocol: gwapiv1.HTTPProtocolType,
+				Port:     80,
+			}},
+		},
+	}
+}
+
+// gatewayAcceptedStatus returns the status of the gateway Accepted condition, or an
+// empty status if the gateway or condition does not exist.
+func gatewayAcceptedStatus(ctx context.Context, key types.NamespacedName) metav1.ConditionStatus {
+	gw := new(gwapiv1.Gateway)
+	if err := k8sClient.Get(ctx, key, gw); err != nil {
+		return ""
+	}
+	cond := meta.FindStatusCondition(gw.Status.Conditions, string(gwapiv1.GatewayConditionAccepted))
+	if cond == nil {
+		return ""
+	}
+	return cond.Status
+}
+
+// gatewayCondition returns the gateway condition of type condType, or nil if the gateway
+// or condition does not exist.
+func gatewayCondition(ctx context.Context, key types.NamespacedName, condType string) *metav1.Condition {
+	gw := new(gwapiv1.Gateway)
+	if err := k8sClient.Get(ctx, key, gw); err != nil {
+		return nil
+	}
+	return meta.FindStatusCondition(gw.Status.Conditions, condType)
+}
+
+func gatewayClassFinalizers(ctx context.Context, name string) []string {
+	gc := new(gwapiv1.GatewayClass)
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, gc); err != nil {
+		return nil
+	}
+	return gc.Finalizers
+}
+
+// endedSpan returns the last ended span of the given name for the named object, or
+// nil if no such span ended.
+func endedSpan(spanName, objName string) sdktrace.ReadOnlySpan {
+	spans := spanRecorder.Ended()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == spanName && hasNameAttribute(spans[i], objName) {
+			return spans[i]
+		}
+	}
+	return nil
+}
+
+// hasNameAttribute returns true if span traces the named object.
+func hasNameAttribute(span sdktrace.ReadOnlySpan, objName string) bool {
+	for _, attr := range span.Attributes() {
+		if attr.Key == tracing.AttrName && attr.Value.AsString() == objName {
+			return true
+		}
+	}
+	return false
+}
@@ -22,15 +22,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
 	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/tracing"
 )
 
 //+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses,verbs=get;list;watch;update;patch
@@ -43,55 +44,93 @@ type GatewayClassReconciler struct {
 	Scheme *runtime.Scheme
 	Config *model.ManagerConfig
 	Log    logr.Logger
+	// APIVersion is the API version used to watch gatewayclasses, defaulting to v1.
+	APIVersion string
 
 	ProcessorChan chan event.GenericEvent
 	ObjectStore   *ObjectStore
 }
 
 func (r *GatewayClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.Log = log.FromContext(context.Background()).WithName("gatewayclass reconciler")
+	r.Log = reconcilerLogger(r.Log, mgr, "gatewayclass reconciler")
+
+	r.ObjectStore.mu.Lock()
+	r.ObjectStore.apiVersions["GatewayClass"] = r.APIVersion
+	r.ObjectStore.mu.Unlock()
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&gwapiv1b1.GatewayClass{}).
+		For(gatewayapi.NewGatewayClass(r.APIVersion)).
 		WithEventFilter(predicate.NewPredicateFuncs(func(obj client.Object) bool {
-			gc, ok := obj.(*gwapiv1b1.GatewayClass)
+			gc, ok := gatewayapi.ToV1GatewayClass(obj)
 			if !ok {
 				return false
 			}
-			return string(gc.Spec.ControllerName) == r.Config.ControllerName
+			return r.Config.Manages(gc)
 		})).
 		Complete(r)
 }
 
 func (r *GatewayClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	r.Log.Info("reconciling request", "name", req.Name)
+	ctx, span := tracing.Tracer().Start(ctx, "GatewayClassReconciler.Reconcile",
+		trace.WithAttributes(tracing.AttrKind.String("GatewayClass"), tracing.AttrName.String(req.Name)))
+	defer span.End()
+
+	ctx, log := requestLogger(ctx, r.Log, "GatewayClass", req)
+	log.V(logLevelDebug).Info("reconciling request")
 
-	gc := new(gwapiv1b1.GatewayClass)
-	if err := r.Client.Get(ctx, req.NamespacedName, gc); err != nil {
+	obj := gatewayapi.NewGatewayClass(r.APIVersion)
+	if err := r.Client.Get(ctx, req.NamespacedName, obj); err != nil {
 		if errors.IsNotFound(err) {
-			r.Log.Info("object no longer exists")
+			log.Info("object no longer exists")
+			r.removeGatewayClass(ctx, req.Name)
 			return ctrl.Result{}, nil
 		}
-		r.Log.Error(err, "failed to get gatewayclass", "name", req.Name)
-		return ctrl.Result{}, err
+		log.Error(err, "failed to get gatewayclass")
+		return ctrl.Result{}, tracing.RecordError(span, err)
 	}
+	// The object is of a supported version.
+	gc, _ := gatewayapi.ToV1GatewayClass(obj)
+	span.SetAttributes(tracing.AttrGeneration.Int64(gc.Generation))
+	ctx, log = withGeneration(ctx, gc)
 
-	if string(gc.Spec.ControllerName) != r.Config.ControllerName {
-		r.Log.Info("gatewayclass controller name doesn't match configuration; bypassing", "name", req.Name)
+	if !r.Config.Manages(gc) {
+		log.V(logLevelDebug).Info("gatewayclass controller name doesn't match configuration; bypassing")
 		return ctrl.Result{}, nil
 	}
 
 	// Process the gatewayclass.
-	existing, ok := r.ObjectStore.gatewayclasses.matched[gc.Name]
-	if !ok || !reflect.DeepEqual(gc, existing) {
-		r.ObjectStore.mu.Lock()
-		defer r.ObjectStore.mu.Unlock()
+	r.ObjectStore.mu.Lock()
+	existing, ok := r.ObjectStore.gatewayclasses.get(gc.Name)
+	changed := !ok || !reflect.DeepEqual(*gc, existing)
+	if changed {
 		r.ObjectStore.gatewayclasses.add(gc)
-		update := event.GenericEvent{Object: gc}
-		r.ProcessorChan <- update
 	}
+	r.ObjectStore.mu.Unlock()
 
-	r.Log.Info("reconciled request", "name", req.Name)
+	if changed {
+		log.Info("gatewayclass changed")
+		r.ObjectStore.sendToProcessor(ctx, r.ProcessorChan, gc)
+	}
+
+	log.V(logLevelDebug).Info("reconciled request")
 
 	return ctrl.Result{}, nil
 }
+
+// removeGatewayClass removes the named gatewayclass from the object store. If the
+// gatewayclass was accepted, the processor is notified of the newly accepted one of
+// its controller.
+func (r *GatewayClassReconciler) removeGatewayClass(ctx context.Context, name string) {
+	r.ObjectStore.mu.Lock()
+	mc := r.ObjectStore.gatewayclasses.remove(name)
+	if mc == nil {
+		r.ObjectStore.mu.Unlock()
+		return
+	}
+	accepted := mc.accepted().DeepCopy()
+	r.ObjectStore.mu.Unlock()
+
+	if accepted.Name != "" {
+		r.ObjectStore.sendToProcessor(ctx, r.ProcessorChan, accepted)
+	}
+}
@@ -0,0 +1,271 @@
+//go:build envtest
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/metrics"
+	"solo.io/sample-gateway-manager/internal/model"
+)
+
+var _ = Describe("GatewayClass controller", func() {
+	ctx := context.Background()
+
+	var created []client.Object
+
+	AfterEach(func() {
+		for _, obj := range created {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, obj))).To(Succeed())
+		}
+		for _, obj := range created {
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+		}
+		created = nil
+	})
+
+	It("accepts a gatewayclass with a matching controllerName", func() {
+		gc := newGatewayClass("accepted", testControllerName)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, gc.Name)
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+	})
+
+	It("records an event when a gatewayclass is accepted", func() {
+		gc := newGatewayClass("event", testControllerName)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Eventually(func() []string {
+			return eventReasons(ctx, "GatewayClass", gc.Name)
+		}, timeout, interval).Should(ContainElement(string(gwapiv1.GatewayClassReasonAccepted)))
+	})
+
+	It("ignores a gatewayclass with a different controllerName", func() {
+		gc := newGatewayClass("ignored", "example.com/other-controller")
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Consistently(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, gc.Name)
+		}, 2*time.Second, interval).Should(Equal(metav1.ConditionUnknown))
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		_, ok := store.gatewayclasses.get(gc.Name)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("only accepts the oldest gatewayclass", func() {
+		older := newGatewayClass("oldest-a", testControllerName)
+		Expect(k8sClient.Create(ctx, older)).To(Succeed())
+		created = append(created, older)
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, older.Name)
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+
+		newer := newGatewayClass("oldest-b", testControllerName)
+		Expect(k8sClient.Create(ctx, newer)).To(Succeed())
+		created = append(created, newer)
+
+		Eventually(func() string {
+			gc := new(gwapiv1.GatewayClass)
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: newer.Name}, gc); err != nil {
+				return ""
+			}
+			cond := meta.FindStatusCondition(gc.Status.Conditions, string(gwapiv1.GatewayClassConditionStatusAccepted))
+			if cond == nil || cond.Status != metav1.ConditionFalse {
+				return ""
+			}
+			return cond.Reason
+		}, timeout, interval).Should(Equal(reasonOlderGatewayClassExists))
+		Expect(gatewayClassAcceptedStatus(ctx, older.Name)).To(Equal(metav1.ConditionTrue))
+
+		By("deleting the oldest gatewayclass")
+		Expect(k8sClient.Delete(ctx, older)).To(Succeed())
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, newer.Name)
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+	})
+
+	It("accepts the oldest gatewayclass of each controller", func() {
+		first := newGatewayClass("controller-a", testControllerName)
+		Expect(k8sClient.Create(ctx, first)).To(Succeed())
+		created = append(created, first)
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, first.Name)
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+
+		second := newGatewayClass("controller-b", testSecondControllerName)
+		Expect(k8sClient.Create(ctx, second)).To(Succeed())
+		created = append(created, second)
+		Eventually(func() metav1.ConditionStatus {
+			return gatewayClassAcceptedStatus(ctx, second.Name)
+		}, timeout, interval).Should(Equal(metav1.ConditionTrue))
+		Expect(gatewayClassAcceptedStatus(ctx, first.Name)).To(Equal(metav1.ConditionTrue))
+
+		Expect(store.Snapshot().AcceptedGatewayClasses).To(And(
+			HaveKeyWithValue(testControllerName, first.Name),
+			HaveKeyWithValue(testSecondControllerName, second.Name)))
+	})
+
+	It("advertises the supported features on the default gatewayclassconfig of the controller", func() {
+		gcc := &cfgv1b1.GatewayClassConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: testDefaultGatewayClassConfig},
+		}
+		Expect(k8sClient.Create(ctx, gcc)).To(Succeed())
+		created = append(created, gcc)
+
+		gc := newGatewayClass("default-config", testSecondControllerName)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Eventually(func() []string {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(gcc), gcc); err != nil {
+				return nil
+			}
+			return gcc.Status.SupportedFeatures
+		}, timeout, interval).Should(ContainElement(string(model.SupportGateway)))
+	})
+
+	It("advertises the supported features on the gatewayclass and the referenced gatewayclassconfig", func() {
+		gcc := &cfgv1b1.GatewayClassConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "features"},
+		}
+		Expect(k8sClient.Create(ctx, gcc)).To(Succeed())
+		created = append(created, gcc)
+
+		gc := newGatewayClass("features", testControllerName)
+		gc.Spec.ParametersRef = &gwapiv1.ParametersReference{
+			Group:     gwapiv1.Group(cfgv1b1.GroupVersion.Group),
+			Kind:      gwapiv1.Kind(gatewayClassConfigKind),
+			Name:      gcc.Name,
+			Namespace: (*gwapiv1.Namespace)(&gcc.Namespace),
+		}
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Eventually(func() []string {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(gcc), gcc); err != nil {
+				return nil
+			}
+			return gcc.Status.SupportedFeatures
+		}, timeout, interval).Should(ContainElement(string(model.SupportGateway)))
+
+		Eventually(func() []gwapiv1.SupportedFeature {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(gc), gc); err != nil {
+				return nil
+			}
+			return gc.Status.SupportedFeatures
+		}, timeout, interval).Should(ContainElement(gwapiv1.SupportedFeature(model.SupportGateway)))
+	})
+
+	It("records metrics of the accepted gatewayclass", func() {
+		gc := newGatewayClass("metrics", testControllerName)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Eventually(func() float64 {
+			return testutil.ToFloat64(metrics.StatusUpdates.WithLabelValues("GatewayClass", metrics.ResultSuccess))
+		}, timeout, interval).Should(BeNumerically(">", 0))
+		Eventually(func() float64 {
+			return testutil.ToFloat64(metrics.ManagedResources.WithLabelValues("GatewayClass", "Accepted", "True"))
+		}, timeout, interval).Should(BeNumerically(">", 0))
+	})
+
+	It("removes a deleted gatewayclass from the object store", func() {
+		gc := newGatewayClass("deleted", testControllerName)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		created = append(created, gc)
+
+		Eventually(func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			_, ok := store.gatewayclasses.get(gc.Name)
+			return ok
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, gc)).To(Succeed())
+
+		Eventually(func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			_, ok := store.gatewayclasses.get(gc.Name)
+			return ok
+		}, timeout, interval).Should(BeFalse())
+	})
+})
+
+func newGatewayClass(name, controllerName string) *gwapiv1.GatewayClass {
+	return &gwapiv1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: gwapiv1.GatewayClassSpec{
+			ControllerName: gwapiv1.GatewayController(controllerName),
+		},
+	}
+}
+
+// gatewayClassAcceptedStatus returns the status of the named gatewayclass Accepted
+// condition, or an empty status if the gatewayclass or condition does not exist.
+func gatewayClassAcceptedStatus(ctx context.Context, name string) metav1.ConditionStatus {
+	gc := new(gwapiv1.GatewayClass)
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, gc); err != nil {
+		return ""
+	}
+	cond := meta.FindStatusCondition(gc.Status.Conditions, string(gwapiv1.GatewayClassConditionStatusAccepted))
+	if cond == nil {
+		return ""
+	}
+	return cond.Status
+}
+
+// eventReasons returns the reasons of the events recorded for the named object of kind.
+func eventReasons(ctx context.Context, kind, name string) []string {
+	list := new(corev1.EventList)
+	if err := k8sClient.List(ctx, list); err != nil {
+		return nil
+	}
+	var reasons []string
+	for _, e := range list.Items {
+		if e.InvolvedObject.Kind == kind && e.InvolvedObject.Name == name {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	return reasons
+}
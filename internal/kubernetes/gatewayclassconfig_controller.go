@@ -18,16 +18,19 @@ package kubernetes
 
 import (
 	"context"
+	"reflect"
+
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/log"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
-	"solo.io/sample-gateway-manager/internal/model"
-
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	cfgv1a1 "solo.io/sample-gateway-manager/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/tracing"
 )
 
 // GatewayClassConfigReconciler reconciles a GatewayClassConfig object
@@ -36,6 +39,9 @@ type GatewayClassConfigReconciler struct {
 	Scheme *runtime.Scheme
 	Config *model.ManagerConfig
 	Log    logr.Logger
+
+	ProcessorChan chan event.GenericEvent
+	ObjectStore   *ObjectStore
 }
 
 //+kubebuilder:rbac:groups=sample.io,resources=gatewayclassconfigs,verbs=get;list;watch;update;patch
@@ -44,32 +50,56 @@ type GatewayClassConfigReconciler struct {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GatewayClassConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	r.Log = log.FromContext(context.Background()).WithName("gatewayclassconfig reconciler")
+	r.Log = reconcilerLogger(r.Log, mgr, "gatewayclassconfig reconciler")
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&cfgv1a1.GatewayClassConfig{}).
+		For(&cfgv1b1.GatewayClassConfig{}).
 		Complete(r)
 }
 
 func (r *GatewayClassConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	r.Log.Info("reconciling request", "namespace", req.Namespace, "name", req.Name)
+	ctx, span := tracing.Tracer().Start(ctx, "GatewayClassConfigReconciler.Reconcile",
+		trace.WithAttributes(tracing.AttrKind.String(gatewayClassConfigKind),
+			tracing.AttrNamespace.String(req.Namespace), tracing.AttrName.String(req.Name)))
+	defer span.End()
 
-	gc := new(gwapiv1b1.GatewayClass)
-	if err := r.Client.Get(ctx, req.NamespacedName, gc); err != nil {
+	ctx, log := requestLogger(ctx, r.Log, gatewayClassConfigKind, req)
+	log.V(logLevelDebug).Info("reconciling request")
+
+	gcc := new(cfgv1b1.GatewayClassConfig)
+	if err := r.Client.Get(ctx, req.NamespacedName, gcc); err != nil {
 		if errors.IsNotFound(err) {
-			r.Log.Info("object no longer exists")
+			log.Info("reconciled object no longer exists")
+			r.ObjectStore.mu.Lock()
+			_, ok := r.ObjectStore.gatewayClassConfigs[req.NamespacedName]
+			delete(r.ObjectStore.gatewayClassConfigs, req.NamespacedName)
+			gateways := r.ObjectStore.gatewaysUsingClassConfig(r.Config, req.NamespacedName)
+			r.ObjectStore.mu.Unlock()
+			if ok {
+				r.ObjectStore.sendGatewaysToProcessor(ctx, r.ProcessorChan, gateways)
+			}
 			return ctrl.Result{}, nil
 		}
-		r.Log.Error(err, "failed to get gatewayclass", "name", req.Name)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, tracing.RecordError(span, err)
 	}
+	span.SetAttributes(tracing.AttrGeneration.Int64(gcc.Generation))
+	ctx, log = withGeneration(ctx, gcc)
+
+	// The gateways of the classes configured by the GatewayClassConfig are processed if
+	// its spec changed. Status updates are made by the processor itself.
+	r.ObjectStore.mu.Lock()
+	current, ok := r.ObjectStore.gatewayClassConfigs[req.NamespacedName]
+	changed := !ok || !reflect.DeepEqual(gcc.Spec, current.Spec)
+	r.ObjectStore.gatewayClassConfigs[req.NamespacedName] = *gcc
+	gateways := r.ObjectStore.gatewaysUsingClassConfig(r.Config, req.NamespacedName)
+	r.ObjectStore.mu.Unlock()
 
-	if string(gc.Spec.ControllerName) != r.Config.ControllerName {
-		r.Log.Info("gatewayclass controller name doesn't match configuration; bypassing", "name", req.Name)
-		return ctrl.Result{}, nil
+	if changed {
+		log.Info("gatewayclassconfig changed", "gateways", len(gateways))
+		r.ObjectStore.sendGatewaysToProcessor(ctx, r.ProcessorChan, gateways)
 	}
 
-	r.Log.Info("reconciled request", "namespace", req.Namespace, "name", req.Name)
+	log.V(logLevelDebug).Info("reconciled request")
 
 	return ctrl.Result{}, nil
 }
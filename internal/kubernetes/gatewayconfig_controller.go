@@ -0,0 +1,104 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/tracing"
+)
+
+const gatewayConfigKind = "GatewayConfig"
+
+//+kubebuilder:rbac:groups=sample.io,resources=gatewayconfigs,verbs=get;list;watch
+
+// GatewayConfigReconciler reconciles the GatewayConfigs referenced by Gateways.
+type GatewayConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config *model.ManagerConfig
+	Log    logr.Logger
+
+	ProcessorChan chan event.GenericEvent
+	ObjectStore   *ObjectStore
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GatewayConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Log = reconcilerLogger(r.Log, mgr, "gatewayconfig reconciler")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cfgv1b1.GatewayConfig{}).
+		Complete(r)
+}
+
+func (r *GatewayConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "GatewayConfigReconciler.Reconcile",
+		trace.WithAttributes(tracing.AttrKind.String(gatewayConfigKind),
+			tracing.AttrNamespace.String(req.Namespace), tracing.AttrName.String(req.Name)))
+	defer span.End()
+
+	ctx, log := requestLogger(ctx, r.Log, gatewayConfigKind, req)
+	log.V(logLevelDebug).Info("reconciling request")
+
+	gwc := new(cfgv1b1.GatewayConfig)
+	if err := r.Client.Get(ctx, req.NamespacedName, gwc); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("reconciled object no longer exists")
+			r.ObjectStore.mu.Lock()
+			_, ok := r.ObjectStore.gatewayConfigs[req.NamespacedName]
+			delete(r.ObjectStore.gatewayConfigs, req.NamespacedName)
+			gateways := r.ObjectStore.gatewaysUsingGatewayConfig(req.NamespacedName)
+			r.ObjectStore.mu.Unlock()
+			if ok {
+				r.ObjectStore.sendGatewaysToProcessor(ctx, r.ProcessorChan, gateways)
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, tracing.RecordError(span, err)
+	}
+	span.SetAttributes(tracing.AttrGeneration.Int64(gwc.Generation))
+	ctx, log = withGeneration(ctx, gwc)
+
+	// Process the gateways referencing the GatewayConfig if its spec changed.
+	r.ObjectStore.mu.Lock()
+	current, ok := r.ObjectStore.gatewayConfigs[req.NamespacedName]
+	changed := !ok || !reflect.DeepEqual(gwc.Spec, current.Spec)
+	r.ObjectStore.gatewayConfigs[req.NamespacedName] = *gwc
+	gateways := r.ObjectStore.gatewaysUsingGatewayConfig(req.NamespacedName)
+	r.ObjectStore.mu.Unlock()
+
+	if changed {
+		log.Info("gatewayconfig changed", "gateways", len(gateways))
+		r.ObjectStore.sendGatewaysToProcessor(ctx, r.ProcessorChan, gateways)
+	}
+
+	log.V(logLevelDebug).Info("reconciled request")
+
+	return ctrl.Result{}, nil
+}
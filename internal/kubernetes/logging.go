@@ -0,0 +1,56 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Verbosity levels of the manager logs. Info logs state changes of the managed
+// objects; debug logs every request.
+const (
+	logLevelDebug = 1
+)
+
+// reconcilerLogger returns the named logger of a reconciler, defaulting to the logger
+// of mgr when log is unset.
+func reconcilerLogger(log logr.Logger, mgr ctrl.Manager, name string) logr.Logger {
+	if log.GetSink() == nil {
+		log = mgr.GetLogger()
+	}
+	return log.WithName(name)
+}
+
+// requestLogger returns the logger of the reconcile of req for an object of kind, and
+// ctx carrying it so the functions called by the reconcile log with the request.
+func requestLogger(ctx context.Context, log logr.Logger, kind string, req ctrl.Request) (context.Context, logr.Logger) {
+	log = log.WithValues("kind", kind, "name", req.Name)
+	if req.Namespace != "" {
+		log = log.WithValues("namespace", req.Namespace)
+	}
+	return logr.NewContext(ctx, log), log
+}
+
+// withGeneration adds the generation of obj to the logger of ctx.
+func withGeneration(ctx context.Context, obj client.Object) (context.Context, logr.Logger) {
+	log := logr.FromContextOrDiscard(ctx).WithValues("generation", obj.GetGeneration())
+	return logr.NewContext(ctx, log), log
+}
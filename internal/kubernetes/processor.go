@@ -2,25 +2,46 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/source"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/metrics"
+	"solo.io/sample-gateway-manager/internal/status"
+	"solo.io/sample-gateway-manager/internal/tracing"
 	"solo.io/sample-gateway-manager/internal/utils/slice"
+	"solo.io/sample-gateway-manager/internal/xds"
 
 	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/provisioner"
 )
 
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
 const (
-	gatewayClassFinalizer = gwapiv1b1.GatewayClassFinalizerGatewaysExist
+	gatewayClassFinalizer = gwapiv1.GatewayClassFinalizerGatewaysExist
 )
 
-// Processor processes managed objects.
+// Processor processes managed objects. The processor runs on every replica to keep
+// the object store and translation warm, but only the leader writes.
 type Processor struct {
 	client.Client
 	Scheme      *runtime.Scheme
@@ -28,62 +49,518 @@ type Processor struct {
 	Log         logr.Logger
 	UpdateChan  chan event.GenericEvent
 	ObjectStore *ObjectStore
+	// StatusUpdater writes the statuses of the managed objects.
+	StatusUpdater *status.Updater
+	// CA issues the xDS certificates of the proxies. It is unused, and may be nil, when
+	// Config.XDSAddress is empty.
+	CA *xds.CA
+	// APIReader reads the Secrets of the proxies, which aren't cached.
+	APIReader client.Reader
+
+	// leading is true once the manager is elected leader.
+	leading atomic.Bool
+
+	// The following are only updated by Reconcile, which isn't called concurrently,
+	// and not guarded by the object store lock since API writes are made without it.
+	// mu guards the updates of provisioned and certificates, which the debug endpoints
+	// read.
+	mu sync.RWMutex
+	// provisioned are the proxies last applied, by the name of their resources.
+	provisioned map[types.NamespacedName]provisionedProxy
+	// draining are the deleted gateways whose proxies are draining.
+	draining map[types.NamespacedName]bool
+	// certificates are the xDS certificates issued to each proxy, by the name of its
+	// resources.
+	certificates map[types.NamespacedName]*xds.Certificate
 }
 
 func (p *Processor) SetupWithManager(mgr ctrl.Manager) error {
-	p.Log = log.FromContext(context.Background()).WithName("processor reconciler")
+	p.Log = reconcilerLogger(p.Log, mgr, "processor reconciler")
+
+	// A runnable func needs leader election, so it starts once the manager is elected.
+	if err := mgr.Add(manager.RunnableFunc(p.startLeading)); err != nil {
+		return err
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("processor").
-		Watches(&source.Channel{Source: p.UpdateChan}, &handler.EnqueueRequestForObject{}).
+		WatchesRawSource(&source.Channel{Source: p.UpdateChan}, &handler.EnqueueRequestForObject{}).
 		Complete(p)
 }
 
 func (p *Processor) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	p.Log.Info("reconciling request", "name", req.Name)
+	p.ObjectStore.mu.Lock()
+	kind := p.requestKind(req)
+	// Link the processor span to the spans of the reconciles that sent the request.
+	var links []trace.Link
+	if pending, ok := p.ObjectStore.enqueued[req.NamespacedName]; ok {
+		metrics.ProcessorQueueLatency.Observe(time.Since(pending.enqueued).Seconds())
+		links = pending.links
+		delete(p.ObjectStore.enqueued, req.NamespacedName)
+	}
+	p.ObjectStore.mu.Unlock()
 
-	p.Log.Info("request", "name", req.Name)
-	p.Log.Info("object", "matched", p.ObjectStore)
+	ctx, log := requestLogger(ctx, p.Log, kind, req)
+	log.V(logLevelDebug).Info("reconciling request")
 
-	if _, ok := p.ObjectStore.gatewayclasses.matched[req.Name]; ok {
-		if err := p.processGatewayClasses(ctx); err != nil {
-			return ctrl.Result{}, err
-		}
-		p.Log.Info("processed gatewayclass", "name", req.Name)
+	ctx, span := tracing.Tracer().Start(ctx, "Processor.Reconcile", trace.WithLinks(links...),
+		trace.WithAttributes(tracing.AttrNamespace.String(req.Namespace), tracing.AttrName.String(req.Name)))
+	defer span.End()
+
+	start := time.Now()
+	res, err := p.translate(ctx, req)
+	metrics.RecordTranslation(start, err)
+
+	if err != nil {
+		log.Error(err, "failed to process request")
+	} else {
+		log.V(logLevelDebug).Info("reconciled request")
 	}
 
+	return res, tracing.RecordError(span, err)
+}
+
+// startLeading marks the processor as leader and reprocesses the managed gatewayclasses,
+// so the writes skipped while standby are made.
+func (p *Processor) startLeading(ctx context.Context) error {
+	p.leading.Store(true)
+	p.Log.Info("elected leader; reprocessing managed gatewayclasses")
+
+	p.ObjectStore.mu.Lock()
+	classes := p.ObjectStore.gatewayclasses.matched()
+	p.ObjectStore.mu.Unlock()
+
+	for i := range classes {
+		p.ObjectStore.sendToProcessor(ctx, p.UpdateChan, &classes[i])
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// requestKind returns the kind of the managed object of req. The object store must be
+// locked by the caller.
+func (p *Processor) requestKind(req ctrl.Request) string {
 	if _, ok := p.ObjectStore.gateways[req.NamespacedName]; ok {
-		if err := p.processGateways(ctx); err != nil {
-			return ctrl.Result{}, err
+		return "Gateway"
+	}
+	return "GatewayClass"
+}
+
+// translate processes the request within the translation span. The object store
+// must not be locked by the caller.
+func (p *Processor) translate(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Processor.translate")
+	defer span.End()
+
+	res, err := p.process(ctx, req)
+	return res, tracing.RecordError(span, err)
+}
+
+// writes are the API writes of a request. They are computed with the object store
+// locked, and made once it is unlocked so the reconcilers storing objects aren't
+// blocked by the API server.
+type writes struct {
+	// drains are the drains of the proxies of the deleted gateways.
+	drains []*gatewayDrain
+	// proxies are the proxies provisioned, by the name of their resources.
+	proxies map[types.NamespacedName]*proxyWrite
+	// deprovisioned are the proxies no gateway uses anymore.
+	deprovisioned []types.NamespacedName
+	// gatewayClassFinalizers are the finalizer updates of the managed gatewayclasses.
+	gatewayClassFinalizers []finalizerWrite
+}
+
+// proxyWrite is the infrastructure of a provisioned proxy.
+type proxyWrite struct {
+	// infra is the infrastructure of a gateway of the proxy, merged onto the shared
+	// proxy if the gateway is merged.
+	infra *provisioner.Infra
+	// version is the API version of the owner of the resources.
+	version string
+	// finalizers add the drain finalizer to the gateways of the proxy missing it.
+	finalizers []finalizerWrite
+}
+
+// gateways returns the names of the gateways served by the proxy.
+func (w *proxyWrite) gateways() []types.NamespacedName {
+	if w.infra.Merged == nil {
+		return []types.NamespacedName{client.ObjectKeyFromObject(w.infra.Gateway)}
+	}
+	var res []types.NamespacedName
+	for _, gw := range w.infra.Merged.Gateways {
+		res = append(res, client.ObjectKeyFromObject(gw))
+	}
+	return res
+}
+
+// resources returns the resources of the proxy connected to the xDS server with conn.
+func (w *proxyWrite) resources(conn *provisioner.XDS) []client.Object {
+	if w.infra.Merged != nil {
+		w.infra.Merged.XDS = conn
+		return w.infra.Merged.Resources(w.version)
+	}
+	w.infra.XDS = conn
+	return w.infra.Resources(w.version)
+}
+
+// finalizerWrite is a patch of the finalizers of a gateway or gatewayclass.
+type finalizerWrite struct {
+	kind   string
+	nsName types.NamespacedName
+	// obj is the object with its new finalizers, of the API version it is written with,
+	// and patch is its patch from the stored object.
+	obj   client.Object
+	patch client.Patch
+	// sync stores the patched object. The object store must be locked by the caller.
+	sync func()
+}
+
+// process translates the object store for the request and makes the resulting writes.
+// The request is requeued while the proxies of deleted gateways drain, and until the
+// xDS certificate of a proxy is renewed. The object store must not be locked by the
+// caller.
+func (p *Processor) process(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	p.ObjectStore.mu.Lock()
+	w, drainAfter := p.plan(ctx, req)
+	p.recordManagedResources()
+	p.ObjectStore.mu.Unlock()
+
+	renewAfter, err := p.write(ctx, w)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: earliest(drainAfter, renewAfter)}, nil
+}
+
+// plan computes the statuses and the writes of the request, or nil if the request
+// isn't of a managed gateway or gatewayclass. It returns the time left until the
+// proxies of a deleted gateway are drained. The object store must be locked by the
+// caller.
+func (p *Processor) plan(ctx context.Context, req ctrl.Request) (*writes, time.Duration) {
+	_, isGatewayClass := p.ObjectStore.gatewayclasses.get(req.Name)
+	_, isGateway := p.ObjectStore.gateways[req.NamespacedName]
+	if !isGatewayClass && !isGateway {
+		return nil, 0
+	}
+
+	// A deleted gateway is sent as a request of its gatewayclass, so gatewayclass requests
+	// process the gateways as well to release the shared proxies it was merged onto.
+	// Gateways determine the gatewayclass finalizer, so gateway requests are processed by
+	// the managed gatewayclasses as well.
+	w := &writes{proxies: map[types.NamespacedName]*proxyWrite{}}
+	drainAfter := p.planGateways(ctx, w)
+	p.planGatewayClasses(ctx, w)
+	return w, drainAfter
+}
+
+// write makes the writes of a request. It returns the time left until the xDS
+// certificate of a proxy is renewed. The object store must not be locked by the caller.
+func (p *Processor) write(ctx context.Context, w *writes) (time.Duration, error) {
+	if w == nil {
+		return 0, nil
+	}
+
+	renewAfter, err := p.writeGateways(ctx, w)
+	if err != nil {
+		return 0, err
+	}
+	logr.FromContextOrDiscard(ctx).V(logLevelDebug).Info("processed gateways")
+
+	if err := p.writeGatewayClasses(ctx, w); err != nil {
+		return 0, err
+	}
+	logr.FromContextOrDiscard(ctx).V(logLevelDebug).Info("processed gatewayclasses")
+
+	return renewAfter, nil
+}
+
+// writeFinalizer patches the finalizers of an object, and keeps the object store in
+// sync with it. The object store must not be locked by the caller.
+func (p *Processor) writeFinalizer(ctx context.Context, fw finalizerWrite) error {
+	if err := p.Patch(ctx, fw.obj, fw.patch); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
 		}
-		p.Log.Info("processed gateway", "namespace", req.Namespace, "name", req.Name)
+		return fmt.Errorf("failed to update the finalizers of %s %s: %w", fw.kind, fw.nsName, err)
 	}
 
-	return ctrl.Result{}, nil
+	p.ObjectStore.mu.Lock()
+	fw.sync()
+	p.ObjectStore.mu.Unlock()
+
+	return nil
 }
 
-func (p *Processor) processGatewayClasses(ctx context.Context) error {
-	for _, gc := range p.ObjectStore.gatewayclasses.matched {
+// recordManagedResources updates the managed resources metric from the object store.
+// The object store must be locked by the caller.
+func (p *Processor) recordManagedResources() {
+	accepted := string(gwapiv1.GatewayClassConditionStatusAccepted)
+	metrics.ManagedResources.Reset()
+	for _, gc := range p.ObjectStore.gatewayclasses.matched() {
+		metrics.ManagedResources.WithLabelValues("GatewayClass", accepted,
+			conditionStatus(gc.Status.Conditions, accepted)).Inc()
+	}
+	for _, gw := range p.ObjectStore.gateways {
+		metrics.ManagedResources.WithLabelValues("Gateway", accepted,
+			conditionStatus(gw.Status.Conditions, accepted)).Inc()
+	}
+	for kind, routes := range p.ObjectStore.routes {
+		for _, route := range routes {
+			metrics.ManagedResources.WithLabelValues(string(kind), accepted, routeAcceptedStatus(route)).Inc()
+		}
+	}
+}
+
+// conditionStatus returns the status of the condition of type condType, or Unknown
+// if the condition is not set.
+func conditionStatus(conds []metav1.Condition, condType string) string {
+	if cond := meta.FindStatusCondition(conds, condType); cond != nil {
+		return string(cond.Status)
+	}
+	return string(metav1.ConditionUnknown)
+}
+
+// planGatewayClasses computes the statuses and finalizer updates of the managed
+// gatewayclasses. The object store must be locked by the caller.
+func (p *Processor) planGatewayClasses(ctx context.Context, w *writes) {
+	for _, gc := range p.ObjectStore.gatewayclasses.matched() {
 		if !gc.DeletionTimestamp.IsZero() &&
 			!slice.ContainsString(gc.Finalizers, gatewayClassFinalizer) {
-			p.Log.Info("gatewayclass marked for deletion")
+			logr.FromContextOrDiscard(ctx).Info("gatewayclass marked for deletion", "gatewayclass", gc.Name)
 			// Delete the gatewayclass from the object store.
-			delete(p.ObjectStore.gatewayclasses.matched, gc.Name)
+			p.ObjectStore.gatewayclasses.remove(gc.Name)
 			continue
 		}
 	}
 
+	// Add or remove the finalizer for all managed gatewayclasses.
+	w.gatewayClassFinalizers = p.gatewayClassFinalizerWrites(ctx)
+
 	// Update status for all managed gatewayclasses.
 	for _, class := range p.ObjectStore.gatewayclasses.all() {
-		if err := p.updateGatewayClassStatus(ctx, &class); err != nil {
-			delete(p.ObjectStore.gatewayclasses.matched, class.Name)
-			return err
+		p.updateGatewayClassStatus(ctx, &class)
+	}
+}
+
+// gatewayClassFinalizerWrites returns the updates adding the gatewayclass finalizer to
+// the managed gatewayclasses referenced by managed gateways, and removing it from the
+// others. Only the leader updates the finalizer. The object store must be locked by
+// the caller.
+func (p *Processor) gatewayClassFinalizerWrites(ctx context.Context) []finalizerWrite {
+	var fws []finalizerWrite
+	for _, gc := range p.ObjectStore.gatewayclasses.matched() {
+		gc := gc
+		hasGateways := p.gatewaysExist(gc.Name)
+		if hasGateways == slice.ContainsString(gc.Finalizers, gatewayClassFinalizer) {
+			continue
 		}
+		if !p.leading.Load() {
+			// Standby replicas reprocess the managed gatewayclasses once elected.
+			logr.FromContextOrDiscard(ctx).V(logLevelDebug).Info("skipping gatewayclass finalizer update on standby replica",
+				"gatewayclass", gc.Name)
+			continue
+		}
+		fws = append(fws, p.gatewayClassFinalizerWrite(&gc, hasGateways))
 	}
+	return fws
+}
 
+// writeGatewayClasses updates the finalizers of the managed gatewayclasses. The object
+// store must not be locked by the caller.
+func (p *Processor) writeGatewayClasses(ctx context.Context, w *writes) error {
+	for _, fw := range w.gatewayClassFinalizers {
+		logr.FromContextOrDiscard(ctx).Info("updating gatewayclass finalizer", "gatewayclass", fw.nsName.Name)
+		if err := p.writeFinalizer(ctx, fw); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (p *Processor) processGateways(ctx context.Context) error {
+// gatewayClassFinalizerWrite returns the update adding the gatewayclass finalizer to gc,
+// or removing it if add is false. The object store must be locked by the caller.
+func (p *Processor) gatewayClassFinalizerWrite(gc *gwapiv1.GatewayClass, add bool) finalizerWrite {
+	base := gc.DeepCopy()
+	gc = gc.DeepCopy()
+	if add {
+		gc.Finalizers = append(gc.Finalizers, gatewayClassFinalizer)
+	} else {
+		gc.Finalizers = slice.RemoveString(gc.Finalizers, gatewayClassFinalizer)
+	}
+
+	return finalizerWrite{
+		kind:   "gatewayclass",
+		nsName: types.NamespacedName{Name: gc.Name},
+		obj:    p.gatewayClassObject(gc),
+		patch:  client.MergeFromWithOptions(p.gatewayClassObject(base), client.MergeFromWithOptimisticLock{}),
+		sync: func() {
+			// A gatewayclass stored since the update was computed is newer.
+			if stored, ok := p.ObjectStore.gatewayclasses.get(gc.Name); ok && stored.ResourceVersion == base.ResourceVersion {
+				p.ObjectStore.gatewayclasses.add(gc)
+			}
+		},
+	}
+}
+
+// gatewaysExist returns true if any managed gateway references the named gatewayclass.
+func (p *Processor) gatewaysExist(className string) bool {
+	for _, gw := range p.ObjectStore.gateways {
+		if gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName) == className {
+			return true
+		}
+	}
+	return false
+}
+
+// planGateways computes the statuses of the gateways, the drains of the proxies of the
+// deleted gateways, and the infrastructure of the other gateways of the accepted
+// gatewayclasses. It returns the time left until the proxies of a deleted gateway are
+// drained. The object store must be locked by the caller.
+func (p *Processor) planGateways(ctx context.Context, w *writes) time.Duration {
+	var drainAfter time.Duration
+	infras := map[types.NamespacedName]*provisioner.Infra{}
+	infraErrs := map[types.NamespacedName]error{}
+	for nsName := range p.ObjectStore.gateways {
+		gw := p.ObjectStore.gateways[nsName]
+		if !gw.DeletionTimestamp.IsZero() {
+			if drain, remaining := p.planDrain(ctx, &gw); drain != nil {
+				w.drains = append(w.drains, drain)
+				drainAfter = earliest(drainAfter, remaining)
+			}
+			continue
+		}
+		if !p.ObjectStore.gatewayclasses.isAccepted(gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName)) {
+			continue
+		}
+		infra, err := p.gatewayInfra(&gw)
+		if err != nil {
+			logr.FromContextOrDiscard(ctx).Info("invalid gateway infrastructure configuration", "gateway", nsName,
+				"error", err.Error())
+			infraErrs[nsName] = err
+			continue
+		}
+		infras[nsName] = infra
+	}
+	for nsName, err := range p.mergeGateways(infras) {
+		logr.FromContextOrDiscard(ctx).Info("gateway not merged", "gateway", nsName, "error", err.Error())
+		infraErrs[nsName] = err
+	}
+
+	// Update status and collect the proxies of the gateways, once for the gateways
+	// sharing a proxy.
+	gwVersion := p.ObjectStore.apiVersion("Gateway")
+	gcVersion := p.ObjectStore.apiVersion("GatewayClass")
+	for nsName := range p.ObjectStore.gateways {
+		gw := p.ObjectStore.gateways[nsName]
+		infra, err := infras[nsName], infraErrs[nsName]
+		if infra == nil && err == nil {
+			continue
+		}
+		if err != nil {
+			infra = nil
+		}
+		p.updateGatewayStatus(ctx, &gw, infra, err)
+		if infra == nil {
+			continue
+		}
+		key := infra.ProxyKey()
+		proxy, ok := w.proxies[key]
+		if !ok {
+			proxy = &proxyWrite{infra: infra, version: gwVersion}
+			if infra.Merged != nil {
+				proxy.version = gcVersion
+			}
+			w.proxies[key] = proxy
+		}
+		if p.leading.Load() && !slice.ContainsString(gw.Finalizers, gatewayFinalizer) {
+			proxy.finalizers = append(proxy.finalizers, p.gatewayFinalizerWrite(&gw, true))
+		}
+	}
+
+	// Delete the proxies no gateway uses anymore: those of deleted gateways and of the
+	// gateways now merged onto a shared proxy, and the shared proxies no gateway is merged
+	// onto. The proxies of gateways with an invalid configuration are kept until it is
+	// fixed, and those of deleted gateways until they are drained.
+	retained := map[types.NamespacedName]bool{}
+	for nsName, gw := range p.ObjectStore.gateways {
+		if infra := infras[nsName]; infra == nil || infra.Config.MergeGateways == nil {
+			retained[types.NamespacedName{Namespace: gw.Namespace, Name: provisioner.ResourceName(gw.Name)}] = true
+		}
+	}
+	for key := range p.provisioned {
+		if _, ok := w.proxies[key]; ok || retained[key] {
+			continue
+		}
+		w.deprovisioned = append(w.deprovisioned, key)
+	}
+
+	// Forget the drained gateways.
+	for nsName := range p.draining {
+		if _, ok := p.ObjectStore.gateways[nsName]; !ok {
+			delete(p.draining, nsName)
+		}
+	}
+
+	return drainAfter
+}
+
+// writeGateways drains the proxies of the deleted gateways, provisions the proxies of
+// the other gateways once their drain finalizer is added, and deletes the proxies no
+// gateway uses anymore. It returns the time left until the xDS certificate of a proxy
+// is renewed. The object store must not be locked by the caller.
+func (p *Processor) writeGateways(ctx context.Context, w *writes) (time.Duration, error) {
+	var errs []error
+
+	for _, drain := range w.drains {
+		if err := p.writeDrain(ctx, drain); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var renewAfter time.Duration
+	for key, proxy := range w.proxies {
+		if err := p.writeFinalizers(ctx, proxy.finalizers); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		conn, err := p.proxyXDS(ctx, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if conn != nil {
+			renewAfter = earliest(renewAfter, time.Until(conn.Certificate.RenewAt()))
+		}
+		if err := p.provision(ctx, key, proxy.gateways(), proxy.resources(conn)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, key := range w.deprovisioned {
+		if err := p.deprovision(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return renewAfter, utilerrors.NewAggregate(errs)
+}
+
+// writeFinalizers makes the finalizer updates fws, and returns the first error.
+func (p *Processor) writeFinalizers(ctx context.Context, fws []finalizerWrite) error {
+	for _, fw := range fws {
+		if err := p.writeFinalizer(ctx, fw); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// earliest returns the earliest of the positive durations a and b, or 0 if neither is
+// positive.
+func earliest(a, b time.Duration) time.Duration {
+	if b > 0 && (a <= 0 || b < a) {
+		return b
+	}
+	return a
+}
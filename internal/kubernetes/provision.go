@@ -0,0 +1,210 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/provisioner"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+
+// fieldOwner is the field manager of the resources provisioned by the manager.
+const fieldOwner = client.FieldOwner("sample-gateway-manager")
+
+// gatewayInfra returns the infrastructure of gw, configured by the GatewayClassConfig of
+// its gatewayclass and the GatewayConfig named by its parameters annotation. The object
+// store must be locked by the caller.
+func (p *Processor) gatewayInfra(gw *gwapiv1.Gateway) (*provisioner.Infra, error) {
+	var classCfg *cfgv1b1.GatewayClassConfigSpec
+	gc, _ := p.ObjectStore.gatewayclasses.get(gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName))
+	if nsName, ok := gatewayClassConfigName(p.Config, &gc); ok {
+		gcc, ok := p.ObjectStore.gatewayClassConfigs[nsName]
+		if !ok {
+			return nil, fmt.Errorf("%s %s of gatewayclass %s not found", gatewayClassConfigKind, nsName, gc.Name)
+		}
+		classCfg = &gcc.Spec
+	}
+
+	// The gateways merged onto a shared proxy aren't configured by a GatewayConfig.
+	var params *cfgv1b1.GatewayConfigSpec
+	if name, ok := gw.Annotations[provisioner.AnnotationParameters]; ok && (classCfg == nil || classCfg.MergeGateways == nil) {
+		nsName := types.NamespacedName{Namespace: gw.Namespace, Name: name}
+		gwc, ok := p.ObjectStore.gatewayConfigs[nsName]
+		if !ok {
+			return nil, fmt.Errorf("%s %s not found", gatewayConfigKind, nsName)
+		}
+		params = &gwc.Spec
+	}
+
+	return provisioner.NewInfra(gw, classCfg, params, p.Config.ProxyImage)
+}
+
+// mergeGateways merges the gateways of infras whose gatewayclass merges gateways onto
+// their shared proxies. The errors of the gateways that couldn't be merged are returned
+// by gateway. The object store must be locked by the caller.
+func (p *Processor) mergeGateways(infras map[types.NamespacedName]*provisioner.Infra) map[types.NamespacedName]error {
+	groups := map[types.NamespacedName][]*provisioner.Infra{}
+	classes := map[types.NamespacedName]gwapiv1.GatewayClass{}
+	for _, infra := range infras {
+		scope := infra.Config.MergeGateways
+		if scope == nil {
+			continue
+		}
+		gc, _ := p.ObjectStore.gatewayclasses.get(gatewayapi.ObjectNameToStr(infra.Gateway.Spec.GatewayClassName))
+		// A gatewayclass that merges gateways is configured by a GatewayClassConfig.
+		cfgName, _ := gatewayClassConfigName(p.Config, &gc)
+		key := provisioner.MergedKey(infra.Gateway, *scope, cfgName.Namespace)
+		groups[key] = append(groups[key], infra)
+		classes[key] = gc
+	}
+
+	errs := map[types.NamespacedName]error{}
+	for key, group := range groups {
+		gc := classes[key]
+		_, conflicts := provisioner.Merge(&gc, key, group)
+		for nsName, err := range conflicts {
+			errs[nsName] = err
+		}
+	}
+	return errs
+}
+
+// provisionedProxy is the infrastructure last applied for a proxy.
+type provisionedProxy struct {
+	// gateways are the gateways served by the proxy.
+	gateways  []types.NamespacedName
+	resources []client.Object
+}
+
+// provision applies desired, the resources of the proxy named key serving gateways,
+// unless they are unchanged since they were last applied. Only the leader provisions.
+// The object store must not be locked by the caller.
+func (p *Processor) provision(ctx context.Context, key types.NamespacedName, gateways []types.NamespacedName, desired []client.Object) error {
+	if !p.leading.Load() {
+		return nil
+	}
+
+	if apiequality.Semantic.DeepEqual(desired, p.provisioned[key].resources) {
+		p.setProvisioned(key, provisionedProxy{gateways: gateways, resources: desired})
+		return nil
+	}
+
+	for _, obj := range desired {
+		// Apply a copy, since the object is updated from the response.
+		applied := obj.DeepCopyObject().(client.Object)
+		if err := p.Patch(ctx, applied, client.Apply, fieldOwner, client.ForceOwnership); err != nil {
+			return fmt.Errorf("failed to apply %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, key, err)
+		}
+	}
+	// Delete the optional resources that are no longer configured.
+	for _, obj := range provisioner.OptionalResources(key) {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if hasKind(desired, kind) {
+			continue
+		}
+		if err := p.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", kind, key, err)
+		}
+	}
+	logr.FromContextOrDiscard(ctx).Info("provisioned gateway infrastructure", "proxy", key)
+	p.setProvisioned(key, provisionedProxy{gateways: gateways, resources: desired})
+
+	return nil
+}
+
+// setProvisioned records the infrastructure last applied for the proxy named key.
+func (p *Processor) setProvisioned(key types.NamespacedName, proxy provisionedProxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provisioned == nil {
+		p.provisioned = map[types.NamespacedName]provisionedProxy{}
+	}
+	p.provisioned[key] = proxy
+}
+
+// deprovision deletes the resources last applied for the proxy named key. The object
+// store must not be locked by the caller.
+func (p *Processor) deprovision(ctx context.Context, key types.NamespacedName) error {
+	for _, obj := range p.provisioned[key].resources {
+		deleted := obj.DeepCopyObject().(client.Object)
+		if err := p.Delete(ctx, deleted); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, key, err)
+		}
+	}
+	logr.FromContextOrDiscard(ctx).Info("deleted gateway infrastructure", "proxy", key)
+	p.mu.Lock()
+	delete(p.provisioned, key)
+	delete(p.certificates, key)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// hasKind returns true if objs has an object of the kind.
+func hasKind(objs []client.Object, kind string) bool {
+	for _, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// GatewayInfrastructure is the infrastructure last provisioned for a gateway.
+type GatewayInfrastructure struct {
+	Gateway types.NamespacedName `json:"gateway"`
+	// Proxy is the name of the resources of the proxy serving the gateway, shared with
+	// the gateways merged with it.
+	Proxy types.NamespacedName `json:"proxy"`
+	// Resources are the kinds and names of the resources applied.
+	Resources []string `json:"resources"`
+}
+
+// Infrastructure returns the infrastructure last provisioned for each gateway, sorted by
+// gateway. Only the leader provisions, so it is empty on standby replicas.
+func (p *Processor) Infrastructure() []GatewayInfrastructure {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	res := []GatewayInfrastructure{}
+	for key, proxy := range p.provisioned {
+		var resources []string
+		for _, obj := range proxy.resources {
+			resources = append(resources, fmt.Sprintf("%s %s",
+				obj.GetObjectKind().GroupVersionKind().Kind, client.ObjectKeyFromObject(obj)))
+		}
+		for _, gw := range proxy.gateways {
+			res = append(res, GatewayInfrastructure{Gateway: gw, Proxy: key, Resources: resources})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Gateway.String() < res[j].Gateway.String() })
+	return res
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/tracing"
+)
+
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tcproutes;tlsroutes;udproutes;grpcroutes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tcproutes/status;tlsroutes/status;udproutes/status;grpcroutes/status,verbs=get;update;patch
+
+// GatedRouteKinds are the route kinds whose reconciler is registered only when the
+// feature gate of the same name is enabled, since their CRDs are experimental.
+var GatedRouteKinds = []gwapiv1.Kind{"TCPRoute", "TLSRoute", "UDPRoute", "GRPCRoute"}
+
+// newRouteObject returns an empty object of the route kind.
+func newRouteObject(kind gwapiv1.Kind) (client.Object, error) {
+	switch kind {
+	case "TCPRoute":
+		return &gwapiv1a2.TCPRoute{}, nil
+	case "TLSRoute":
+		return &gwapiv1a2.TLSRoute{}, nil
+	case "UDPRoute":
+		return &gwapiv1a2.UDPRoute{}, nil
+	case "GRPCRoute":
+		return &gwapiv1a2.GRPCRoute{}, nil
+	}
+	return nil, fmt.Errorf("unsupported route kind %s", kind)
+}
+
+// newRouteList returns an empty list of the route kind.
+func newRouteList(kind gwapiv1.Kind) (client.ObjectList, error) {
+	switch kind {
+	case "TCPRoute":
+		return &gwapiv1a2.TCPRouteList{}, nil
+	case "TLSRoute":
+		return &gwapiv1a2.TLSRouteList{}, nil
+	case "UDPRoute":
+		return &gwapiv1a2.UDPRouteList{}, nil
+	case "GRPCRoute":
+		return &gwapiv1a2.GRPCRouteList{}, nil
+	}
+	return nil, fmt.Errorf("unsupported route kind %s", kind)
+}
+
+// RouteReconciler reconciles route objects of a single kind.
+type RouteReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config *model.ManagerConfig
+	Log    logr.Logger
+	// Kind is the kind of route reconciled, e.g. TCPRoute.
+	Kind gwapiv1.Kind
+
+	ObjectStore *ObjectStore
+}
+
+func (r *RouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Log = reconcilerLogger(r.Log, mgr, fmt.Sprintf("%s reconciler", r.Kind))
+
+	obj, err := newRouteObject(r.Kind)
+	if err != nil {
+		return err
+	}
+
+	r.ObjectStore.mu.Lock()
+	if _, ok := r.ObjectStore.routes[r.Kind]; !ok {
+		r.ObjectStore.routes[r.Kind] = map[types.NamespacedName]client.Object{}
+	}
+	// The gateways are notified once stored, since watching them would race with the
+	// gateway reconciler.
+	gatewayChan, ok := r.ObjectStore.routeChans[r.Kind]
+	if !ok {
+		gatewayChan = make(chan event.GenericEvent)
+		r.ObjectStore.routeChans[r.Kind] = gatewayChan
+	}
+	r.ObjectStore.mu.Unlock()
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(string(r.Kind)).
+		For(obj).
+		WatchesRawSource(&source.Channel{Source: gatewayChan},
+			handler.EnqueueRequestsFromMapFunc(r.mapGatewayToRoutes)).
+		Complete(r)
+}
+
+// mapGatewayToRoutes returns the requests of the routes with a parentRef to the
+// gateway.
+func (r *RouteReconciler) mapGatewayToRoutes(ctx context.Context, gw client.Object) []reconcile.Request {
+	// The kind is known to be supported by SetupWithManager.
+	list, _ := newRouteList(r.Kind)
+	if err := r.Client.List(ctx, list); err != nil {
+		r.Log.Error(err, "failed to list routes", "kind", r.Kind)
+		return nil
+	}
+	routes, err := apimeta.ExtractList(list)
+	if err != nil {
+		r.Log.Error(err, "failed to extract routes", "kind", r.Kind)
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, obj := range routes {
+		route := obj.(client.Object)
+		for _, ref := range routeParentRefs(route) {
+			if key, ok := parentRefGateway(route, ref); ok && key == client.ObjectKeyFromObject(gw) {
+				reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(route)})
+				break
+			}
+		}
+	}
+	return reqs
+}
+
+func (r *RouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "RouteReconciler.Reconcile",
+		trace.WithAttributes(tracing.AttrKind.String(string(r.Kind)),
+			tracing.AttrNamespace.String(req.Namespace), tracing.AttrName.String(req.Name)))
+	defer span.End()
+
+	ctx, log := requestLogger(ctx, r.Log, string(r.Kind), req)
+	log.V(logLevelDebug).Info("reconciling request")
+
+	// The kind is known to be supported by SetupWithManager.
+	route, _ := newRouteObject(r.Kind)
+	if err := r.Client.Get(ctx, req.NamespacedName, route); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("reconciled object no longer exists")
+			r.ObjectStore.mu.Lock()
+			delete(r.ObjectStore.routes[r.Kind], req.NamespacedName)
+			r.ObjectStore.mu.Unlock()
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, tracing.RecordError(span, err)
+	}
+	span.SetAttributes(tracing.AttrGeneration.Int64(route.GetGeneration()))
+	ctx, log = withGeneration(ctx, route)
+
+	// Store the route if it is attached to a managed gateway and differs from the
+	// internal store. Routes aren't translated yet, so the processor isn't notified.
+	r.ObjectStore.mu.Lock()
+	current, ok := r.ObjectStore.routes[r.Kind][req.NamespacedName]
+	attached := r.ObjectStore.routeAttached(route)
+	changed := attached && (!ok || !reflect.DeepEqual(route, current))
+	if changed {
+		r.ObjectStore.routes[r.Kind][req.NamespacedName] = route
+	}
+	if ok && !attached {
+		delete(r.ObjectStore.routes[r.Kind], req.NamespacedName)
+	}
+	r.ObjectStore.mu.Unlock()
+
+	switch {
+	case changed:
+		log.Info("route changed")
+	case ok && !attached:
+		log.Info("route detached from the managed gateways")
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// routeParentRefs returns the parentRefs of route.
+func routeParentRefs(route client.Object) []gwapiv1a2.ParentReference {
+	switch r := route.(type) {
+	case *gwapiv1a2.TCPRoute:
+		return r.Spec.ParentRefs
+	case *gwapiv1a2.TLSRoute:
+		return r.Spec.ParentRefs
+	case *gwapiv1a2.UDPRoute:
+		return r.Spec.ParentRefs
+	case *gwapiv1a2.GRPCRoute:
+		return r.Spec.ParentRefs
+	}
+	return nil
+}
+
+// parentRefGateway returns the name of the gateway referenced by the parentRef of
+// route, or false if ref isn't a gateway.
+func parentRefGateway(route client.Object, ref gwapiv1a2.ParentReference) (types.NamespacedName, bool) {
+	if ref.Group != nil && *ref.Group != gwapiv1.GroupName {
+		return types.NamespacedName{}, false
+	}
+	if ref.Kind != nil && *ref.Kind != "Gateway" {
+		return types.NamespacedName{}, false
+	}
+	key := types.NamespacedName{Namespace: route.GetNamespace(), Name: string(ref.Name)}
+	if ref.Namespace != nil {
+		key.Namespace = string(*ref.Namespace)
+	}
+	return key, true
+}
+
+// routeAcceptedStatus returns True if any parent accepted route, False if all
+// parents rejected it, or Unknown if no parent reported its status.
+func routeAcceptedStatus(route client.Object) string {
+	var parents []gwapiv1a2.RouteParentStatus
+	switch r := route.(type) {
+	case *gwapiv1a2.TCPRoute:
+		parents = r.Status.Parents
+	case *gwapiv1a2.TLSRoute:
+		parents = r.Status.Parents
+	case *gwapiv1a2.UDPRoute:
+		parents = r.Status.Parents
+	case *gwapiv1a2.GRPCRoute:
+		parents = r.Status.Parents
+	}
+
+	res := string(metav1.ConditionUnknown)
+	for _, parent := range parents {
+		switch conditionStatus(parent.Conditions, string(gwapiv1.RouteConditionAccepted)) {
+		case string(metav1.ConditionTrue):
+			return string(metav1.ConditionTrue)
+		case string(metav1.ConditionFalse):
+			res = string(metav1.ConditionFalse)
+		}
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+//go:build envtest
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+var _ = Describe("Route controller", func() {
+	ctx := context.Background()
+
+	It("stores the routes of an enabled kind attached to a managed gateway", func() {
+		gcc := &cfgv1b1.GatewayClassConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "route-test"},
+			Spec: cfgv1b1.GatewayClassConfigSpec{
+				Deployment: &cfgv1b1.ProxyDeployment{DrainTimeout: &metav1.Duration{}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, gcc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, gcc))).To(Succeed())
+		})
+
+		gc := newGatewayClass("route-test", testControllerName)
+		gc.Spec.ParametersRef = gatewayClassConfigRef(gcc)
+		Expect(k8sClient.Create(ctx, gc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, gc))).To(Succeed())
+		})
+
+		route := &gwapiv1a2.TCPRoute{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tcp"},
+			Spec: gwapiv1a2.TCPRouteSpec{
+				CommonRouteSpec: gwapiv1a2.CommonRouteSpec{
+					ParentRefs: []gwapiv1a2.ParentReference{{Name: "route-test"}},
+				},
+				Rules: []gwapiv1a2.TCPRouteRule{{
+					BackendRefs: []gwapiv1a2.BackendRef{{
+						BackendObjectReference: gwapiv1a2.BackendObjectReference{Name: "backend"},
+					}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, route)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, route))).To(Succeed())
+		})
+
+		By("ignoring the route while its gateway doesn't exist")
+		Consistently(func() bool {
+			return routeStored("TCPRoute", client.ObjectKeyFromObject(route))
+		}, time.Second, interval).Should(BeFalse())
+
+		By("storing the route once its gateway is managed")
+		gw := newGateway("default", "route-test", gc.Name)
+		Expect(k8sClient.Create(ctx, gw)).To(Succeed())
+		Eventually(func() bool {
+			return routeStored("TCPRoute", client.ObjectKeyFromObject(route))
+		}, timeout, interval).Should(BeTrue())
+
+		By("removing the route once its gateway is deleted")
+		Expect(k8sClient.Delete(ctx, gw)).To(Succeed())
+		Eventually(func() bool {
+			return routeStored("TCPRoute", client.ObjectKeyFromObject(route))
+		}, timeout, interval).Should(BeFalse())
+	})
+})
+
+func routeStored(kind string, key types.NamespacedName) bool {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	_, ok := store.routes[gwapiv1a2.Kind(kind)][key]
+	return ok
+}
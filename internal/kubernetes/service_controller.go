@@ -0,0 +1,104 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/provisioner"
+	"solo.io/sample-gateway-manager/internal/tracing"
+)
+
+// ServiceReconciler reconciles the provisioned Services, whose addresses are the
+// addresses of the gateways they expose.
+type ServiceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config *model.ManagerConfig
+	Log    logr.Logger
+
+	ProcessorChan chan event.GenericEvent
+	ObjectStore   *ObjectStore
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Log = reconcilerLogger(r.Log, mgr, "service reconciler")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Service{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return provisioner.IsProvisioned(obj)
+		}))).
+		Complete(r)
+}
+
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ServiceReconciler.Reconcile",
+		trace.WithAttributes(tracing.AttrKind.String("Service"),
+			tracing.AttrNamespace.String(req.Namespace), tracing.AttrName.String(req.Name)))
+	defer span.End()
+
+	ctx, log := requestLogger(ctx, r.Log, "Service", req)
+	log.V(logLevelDebug).Info("reconciling request")
+
+	svc := new(corev1.Service)
+	if err := r.Client.Get(ctx, req.NamespacedName, svc); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("reconciled object no longer exists")
+			r.ObjectStore.mu.Lock()
+			current, ok := r.ObjectStore.services[req.NamespacedName]
+			delete(r.ObjectStore.services, req.NamespacedName)
+			gateways := r.ObjectStore.gatewaysUsingService(&current)
+			r.ObjectStore.mu.Unlock()
+			if ok {
+				r.ObjectStore.sendGatewaysToProcessor(ctx, r.ProcessorChan, gateways)
+			}
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, tracing.RecordError(span, err)
+	}
+
+	// Process the gateways exposed by the Service if its spec or status changed.
+	r.ObjectStore.mu.Lock()
+	current, ok := r.ObjectStore.services[req.NamespacedName]
+	changed := !ok || !reflect.DeepEqual(svc.Spec, current.Spec) || !reflect.DeepEqual(svc.Status, current.Status)
+	r.ObjectStore.services[req.NamespacedName] = *svc
+	gateways := r.ObjectStore.gatewaysUsingService(svc)
+	r.ObjectStore.mu.Unlock()
+
+	if changed {
+		log.Info("service changed", "gateways", len(gateways))
+		r.ObjectStore.sendGatewaysToProcessor(ctx, r.ProcessorChan, gateways)
+	}
+
+	log.V(logLevelDebug).Info("reconciled request")
+
+	return ctrl.Result{}, nil
+}
@@ -2,29 +2,39 @@ package kubernetes
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/provisioner"
+	"solo.io/sample-gateway-manager/internal/status"
 )
 
 const (
+	gatewayClassConfigKind = "GatewayClassConfig"
+
 	reasonOlderGatewayClassExists = "OlderGatewayClassExists"
 	msgOlderGatewayClassExists    = "An older GatewayClass with the same controller exists"
+	reasonUnsupportedVersion      = "UnsupportedVersion"
+	reasonInvalidParameters       = "InvalidParameters"
 )
 
 func (p *Processor) updateStatus(ctx context.Context, obj client.Object) error {
 	// Determine the object type to update.
-	switch obj {
-	case obj.(*gwapiv1b1.GatewayClass):
-		gc, ok := obj.(*gwapiv1b1.GatewayClass)
-		if !ok {
-			return fmt.Errorf("failed to cast object: %v", obj)
-		}
-		if err := p.updateGatewayClassStatus(ctx, gc); err != nil {
-			return err
-		}
+	switch o := obj.(type) {
+	case *gwapiv1.GatewayClass:
+		p.updateGatewayClassStatus(ctx, o)
+	case *gwapiv1.Gateway:
+		infra, err := p.gatewayInfra(o)
+		p.updateGatewayStatus(ctx, o, infra, err)
 	default:
 		return fmt.Errorf("unknown object kind: %v", obj.GetObjectKind())
 	}
@@ -32,68 +42,189 @@ func (p *Processor) updateStatus(ctx context.Context, obj client.Object) error {
 	return nil
 }
 
-func (p *Processor) updateGatewayClassStatus(ctx context.Context, gc *gwapiv1b1.GatewayClass) error {
-	// The gatewayclass was already deleted/finalized and there are stale queue entries.
-	accepted := p.ObjectStore.gatewayclasses.accepted()
+func (p *Processor) updateGatewayClassStatus(ctx context.Context, gc *gwapiv1.GatewayClass) {
+	accepted := p.ObjectStore.gatewayclasses.controller(gc.Spec.ControllerName).accepted()
 	if accepted.Name == gc.Name {
-		//if !status.IsEqual(accepted, gc) {
+		if err := p.ObjectStore.incompatibleCRDs(); err != nil {
+			p.setNotAcceptedStatus(ctx, gc, reasonUnsupportedVersion,
+				fmt.Sprintf("Incompatible Gateway API CRDs are installed: %v", err))
+			return
+		}
 		p.setAcceptedStatus(ctx, gc)
-		//}
+		p.updateGatewayClassConfigStatus(ctx, gc)
+		return
 	}
 
-	/*all := p.ObjectStore.gatewayclasses.all()
-	for _, class := range all {
-		if class.Name == accepted.Name ||
-			class.Name != gc.Name {
-			continue
-		}
-		if !status.IsEqual(class, gc) {
-			p.setNotAcceptedStatus(ctx, gc)
-		}
-	}*/
-
-	// No Status updated needed.
-	return nil
+	p.setNotAcceptedStatus(ctx, gc, reasonOlderGatewayClassExists, msgOlderGatewayClassExists)
 }
 
-func (p *Processor) setAcceptedStatus(ctx context.Context, gc *gwapiv1b1.GatewayClass) error {
-	//status.ComputeGatewayClassAcceptedCondition(gc, true, )
-	copy := gc.DeepCopy()
+func (p *Processor) setAcceptedStatus(ctx context.Context, gc *gwapiv1.GatewayClass) {
 	acceptedCond := metav1.Condition{
-		Type:               string(gwapiv1b1.GatewayClassConditionStatusAccepted),
+		Type:               string(gwapiv1.GatewayClassConditionStatusAccepted),
 		Status:             metav1.ConditionTrue,
 		ObservedGeneration: gc.Generation,
 		LastTransitionTime: metav1.Now(),
-		Reason:             string(gwapiv1b1.GatewayClassReasonAccepted),
+		Reason:             string(gwapiv1.GatewayClassReasonAccepted),
 		Message:            "gatewayclass is accepted",
 	}
-	setCondition(acceptedCond, gc)
-	return p.Status().Patch(ctx, gc, client.MergeFrom(copy))
+	var features []gwapiv1.SupportedFeature
+	for _, f := range model.SupportedFeatures() {
+		features = append(features, gwapiv1.SupportedFeature(f))
+	}
+
+	p.sendGatewayClassStatus(ctx, gc, func(gc *gwapiv1.GatewayClass) {
+		gc.Status.Conditions = status.MergeConditions(gc.Status.Conditions, acceptedCond)
+		gc.Status.SupportedFeatures = features
+	})
 }
 
-func (p *Processor) setNotAcceptedStatus(ctx context.Context, gc *gwapiv1b1.GatewayClass) error {
-	copy := gc.DeepCopy()
+func (p *Processor) setNotAcceptedStatus(ctx context.Context, gc *gwapiv1.GatewayClass, reason, msg string) {
 	acceptedCond := metav1.Condition{
-		Type:               string(gwapiv1b1.GatewayClassConditionStatusAccepted),
+		Type:               string(gwapiv1.GatewayClassConditionStatusAccepted),
 		Status:             metav1.ConditionFalse,
 		ObservedGeneration: gc.Generation,
 		LastTransitionTime: metav1.Now(),
-		Reason:             reasonOlderGatewayClassExists,
-		Message:            msgOlderGatewayClassExists,
+		Reason:             reason,
+		Message:            msg,
 	}
-	setCondition(acceptedCond, gc)
-	return p.Status().Patch(ctx, gc, client.MergeFrom(copy))
+
+	p.sendGatewayClassStatus(ctx, gc, func(gc *gwapiv1.GatewayClass) {
+		gc.Status.Conditions = status.MergeConditions(gc.Status.Conditions, acceptedCond)
+		// Only the accepted gatewayclass advertises the supported features.
+		gc.Status.SupportedFeatures = nil
+	})
 }
 
-func setCondition(condition metav1.Condition, gc *gwapiv1b1.GatewayClass) {
-	newConds := make([]metav1.Condition, 0, len(gc.Status.Conditions))
+// updateGatewayClassConfigStatus mirrors the supported features to the GatewayClassConfig
+// of gc.
+func (p *Processor) updateGatewayClassConfigStatus(ctx context.Context, gc *gwapiv1.GatewayClass) {
+	nsName, ok := gatewayClassConfigName(p.Config, gc)
+	if !ok {
+		return
+	}
+
+	var features []string
+	for _, f := range model.SupportedFeatures() {
+		features = append(features, string(f))
+	}
+
+	p.StatusUpdater.Send(ctx, status.Update{
+		Kind:           gatewayClassConfigKind,
+		NamespacedName: nsName,
+		Resource:       new(cfgv1b1.GatewayClassConfig),
+		Mutate: func(obj client.Object) {
+			gcc := obj.(*cfgv1b1.GatewayClassConfig)
+			gcc.Status.SupportedFeatures = features
+		},
+	})
+}
 
-	for i := 0; i < len(gc.Status.Conditions); i++ {
-		if gc.Status.Conditions[i].Type != condition.Type {
-			newConds = append(newConds, gc.Status.Conditions[i])
+// gatewayClassConfigName returns the name of the GatewayClassConfig referenced by the
+// parametersRef of gc, or of the default GatewayClassConfig of its controller in cfg
+// if gc has no parametersRef.
+func gatewayClassConfigName(cfg *model.ManagerConfig, gc *gwapiv1.GatewayClass) (types.NamespacedName, bool) {
+	ref := gc.Spec.ParametersRef
+	if ref == nil {
+		if c := cfg.Controller(gc.Spec.ControllerName); c != nil && c.DefaultGatewayClassConfig != nil {
+			return *c.DefaultGatewayClassConfig, true
 		}
+		return types.NamespacedName{}, false
+	}
+	if ref.Namespace == nil ||
+		string(ref.Group) != cfgv1b1.GroupVersion.Group || string(ref.Kind) != gatewayClassConfigKind {
+		return types.NamespacedName{}, false
 	}
+	return types.NamespacedName{Namespace: string(*ref.Namespace), Name: ref.Name}, true
+}
+
+// updateGatewayStatus accepts gw, or rejects it with the error computing infra, its
+// infrastructure, or merging it onto a shared proxy. An accepted gateway is programmed
+// once its Service is assigned the requested addresses. The object store must be locked by the caller.
+func (p *Processor) updateGatewayStatus(ctx context.Context, gw *gwapiv1.Gateway, infra *provisioner.Infra, infraErr error) {
+	acceptedCond := metav1.Condition{
+		Type:               string(gwapiv1.GatewayConditionAccepted),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: gw.Generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             string(gwapiv1.GatewayReasonAccepted),
+		Message:            "gateway is accepted",
+	}
+	programmedCond := metav1.Condition{
+		Type:               string(gwapiv1.GatewayConditionProgrammed),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: gw.Generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             string(gwapiv1.GatewayReasonProgrammed),
+		Message:            "gateway is programmed",
+	}
+	var addrs []gwapiv1.GatewayStatusAddress
+
+	var addrErr *provisioner.UnsupportedAddressError
+	var conflictErr *provisioner.ListenerConflictError
+	switch {
+	case infraErr != nil:
+		acceptedCond.Status = metav1.ConditionFalse
+		acceptedCond.Reason = reasonInvalidParameters
+		if errors.As(infraErr, &addrErr) {
+			acceptedCond.Reason = string(gwapiv1.GatewayReasonUnsupportedAddress)
+		} else if errors.As(infraErr, &conflictErr) {
+			acceptedCond.Reason = string(gwapiv1.GatewayReasonListenersNotValid)
+		}
+		acceptedCond.Message = infraErr.Error()
+		programmedCond.Status = metav1.ConditionFalse
+		programmedCond.Reason = string(gwapiv1.GatewayReasonInvalid)
+		programmedCond.Message = "gateway is not accepted"
+	default:
+		var svc *corev1.Service
+		if current, ok := p.ObjectStore.services[infra.ProxyKey()]; ok {
+			svc = &current
+		}
+		var programmed bool
+		addrs, programmed = infra.Addresses(svc)
+		if !programmed {
+			programmedCond.Status = metav1.ConditionFalse
+			programmedCond.Reason = string(gwapiv1.GatewayReasonAddressNotAssigned)
+			programmedCond.Message = "waiting for the Service of the gateway to be assigned an address"
+			if len(infra.IPs) > 0 {
+				programmedCond.Message = fmt.Sprintf("waiting for the Service of the gateway to be assigned the requested addresses %v",
+					infra.IPs)
+			}
+		}
+	}
+
+	p.StatusUpdater.Send(ctx, status.Update{
+		Kind:           "Gateway",
+		NamespacedName: client.ObjectKeyFromObject(gw),
+		Resource:       gatewayapi.NewGateway(p.ObjectStore.apiVersion("Gateway")),
+		Mutate: func(obj client.Object) {
+			// The object is of a supported version.
+			gw, _ := gatewayapi.ToV1Gateway(obj)
+			gw.Status.Conditions = status.MergeConditions(gw.Status.Conditions, acceptedCond, programmedCond)
+			gw.Status.Addresses = addrs
+		},
+	})
+}
+
+// sendGatewayClassStatus sends the status applied by mutate to gc to the status updater.
+func (p *Processor) sendGatewayClassStatus(ctx context.Context, gc *gwapiv1.GatewayClass, mutate func(*gwapiv1.GatewayClass)) {
+	p.StatusUpdater.Send(ctx, status.Update{
+		Kind:           "GatewayClass",
+		NamespacedName: client.ObjectKeyFromObject(gc),
+		Resource:       gatewayapi.NewGatewayClass(p.ObjectStore.apiVersion("GatewayClass")),
+		Mutate: func(obj client.Object) {
+			// The object is of a supported version.
+			gc, _ := gatewayapi.ToV1GatewayClass(obj)
+			mutate(gc)
+		},
+	})
+}
+
+// gatewayClassObject returns gc as an object of the API version used to write gatewayclasses.
+func (p *Processor) gatewayClassObject(gc *gwapiv1.GatewayClass) client.Object {
+	return gatewayapi.FromV1GatewayClass(gc, p.ObjectStore.apiVersion("GatewayClass"))
+}
 
-	newConds = append(newConds, condition)
-	gc.Status.Conditions = newConds
+// gatewayObject returns gw as an object of the API version used to write gateways.
+func (p *Processor) gatewayObject(gw *gwapiv1.Gateway) client.Object {
+	return gatewayapi.FromV1Gateway(gw, p.ObjectStore.apiVersion("Gateway"))
 }
@@ -1,72 +1,213 @@
 package kubernetes
 
 import (
+	"context"
+	"sort"
 	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/provisioner"
+	"solo.io/sample-gateway-manager/internal/tracing"
 )
 
 type ObjectStore struct {
 	mu sync.Mutex
-	// Map for storing managed gatewayclasses.
-	gatewayclasses *managedClasses
+	// Map for storing managed gatewayclasses by controllerName.
+	gatewayclasses controllerClasses
 	// Map for storing managed gateways.
-	gateways map[types.NamespacedName]gwapiv1b1.Gateway
+	gateways map[types.NamespacedName]gwapiv1.Gateway
+	// Map for storing the provisioned Services.
+	services map[types.NamespacedName]corev1.Service
+	// Map for storing the routes attached to the managed gateways by kind.
+	routes map[gwapiv1.Kind]map[types.NamespacedName]client.Object
+	// Map for storing the channels notifying the route reconcilers of stored and removed
+	// gateways by kind.
+	routeChans map[gwapiv1.Kind]chan event.GenericEvent
+	// Map for storing GatewayClassConfigs.
+	gatewayClassConfigs map[types.NamespacedName]cfgv1b1.GatewayClassConfig
+	// Map for storing GatewayConfigs.
+	gatewayConfigs map[types.NamespacedName]cfgv1b1.GatewayConfig
+	// Map for storing installed Gateway API CRDs by kind.
+	crds map[gwapiv1.Kind]gatewayapi.CRD
+	// Map for storing the API version used to read and write each kind.
+	apiVersions map[gwapiv1.Kind]string
+	// Map for storing the pending requests of the processor.
+	enqueued map[types.NamespacedName]*pendingRequest
+}
+
+// pendingRequest is a request sent to the processor but not yet processed.
+type pendingRequest struct {
+	// enqueued is the time the processor was first notified of the request.
+	enqueued time.Time
+	// links are the spans of the reconciles that sent the request.
+	links []trace.Link
 }
 
 type managedClasses struct {
-	matched map[string]gwapiv1b1.GatewayClass
-	oldest  *gwapiv1b1.GatewayClass
+	matched map[string]gwapiv1.GatewayClass
+	oldest  *gwapiv1.GatewayClass
 }
 
 func NewObjectStore() *ObjectStore {
 	return &ObjectStore{
-		gatewayclasses: &managedClasses{
-			matched: make(map[string]gwapiv1b1.GatewayClass),
-			oldest:  new(gwapiv1b1.GatewayClass),
-		},
-		gateways: map[types.NamespacedName]gwapiv1b1.Gateway{},
+		gatewayclasses: controllerClasses{},
+		gateways:       map[types.NamespacedName]gwapiv1.Gateway{},
+		services:       map[types.NamespacedName]corev1.Service{},
+		routes:         map[gwapiv1.Kind]map[types.NamespacedName]client.Object{},
+		routeChans:     map[gwapiv1.Kind]chan event.GenericEvent{},
+		crds:           map[gwapiv1.Kind]gatewayapi.CRD{},
+		apiVersions:    map[gwapiv1.Kind]string{},
+		enqueued:       map[types.NamespacedName]*pendingRequest{},
+
+		gatewayClassConfigs: map[types.NamespacedName]cfgv1b1.GatewayClassConfig{},
+		gatewayConfigs:      map[types.NamespacedName]cfgv1b1.GatewayConfig{},
+	}
+}
+
+// controllerClasses stores the managed gatewayclasses by controllerName. Each controller
+// accepts the oldest of its gatewayclasses independently of the other controllers.
+type controllerClasses map[string]*managedClasses
+
+// controller returns the gatewayclasses of the named controller.
+func (cc controllerClasses) controller(name gwapiv1.GatewayController) *managedClasses {
+	mc, ok := cc[string(name)]
+	if !ok {
+		mc = &managedClasses{
+			matched: make(map[string]gwapiv1.GatewayClass),
+			oldest:  new(gwapiv1.GatewayClass),
+		}
+		cc[string(name)] = mc
+	}
+	return mc
+}
+
+// get returns the named gatewayclass of any controller.
+func (cc controllerClasses) get(name string) (gwapiv1.GatewayClass, bool) {
+	for _, mc := range cc {
+		if gc, ok := mc.matched[name]; ok {
+			return gc, true
+		}
+	}
+	return gwapiv1.GatewayClass{}, false
+}
+
+func (cc controllerClasses) add(gc *gwapiv1.GatewayClass) {
+	cc.controller(gc.Spec.ControllerName).add(gc)
+}
+
+// remove removes the named gatewayclass and returns the gatewayclasses of its
+// controller, or nil if the gatewayclass isn't managed.
+func (cc controllerClasses) remove(name string) *managedClasses {
+	for _, mc := range cc {
+		if _, ok := mc.matched[name]; ok {
+			mc.remove(&gwapiv1.GatewayClass{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			return mc
+		}
+	}
+	return nil
+}
+
+// matched returns the gatewayclasses of all controllers.
+func (cc controllerClasses) matched() []gwapiv1.GatewayClass {
+	var res []gwapiv1.GatewayClass
+	for _, mc := range cc {
+		for _, gc := range mc.matched {
+			res = append(res, gc)
+		}
+	}
+	return res
+}
+
+// accepted returns the accepted gatewayclass of each controller.
+func (cc controllerClasses) accepted() []gwapiv1.GatewayClass {
+	var res []gwapiv1.GatewayClass
+	for _, mc := range cc {
+		if accepted := mc.accepted(); accepted.Name != "" {
+			res = append(res, *accepted)
+		}
+	}
+	return res
+}
+
+// isAccepted returns true if the named gatewayclass is accepted by its controller.
+func (cc controllerClasses) isAccepted(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, mc := range cc {
+		if mc.accepted().Name == name {
+			return true
+		}
 	}
+	return false
+}
+
+// all returns the gatewayclasses of all controllers, the accepted gatewayclass of
+// each controller first.
+func (cc controllerClasses) all() []gwapiv1.GatewayClass {
+	var res []gwapiv1.GatewayClass
+	for _, mc := range cc {
+		res = append(res, mc.all()...)
+	}
+	return res
 }
 
-func (mc *managedClasses) add(gc *gwapiv1b1.GatewayClass) {
+func (mc *managedClasses) add(gc *gwapiv1.GatewayClass) {
 	mc.matched[gc.Name] = *gc
 
 	switch {
-	case gc.CreationTimestamp.Time.Before(mc.oldest.CreationTimestamp.Time):
+	case mc.oldest.Name == "" || mc.oldest.Name == gc.Name:
+		// Either no gatewayclass is accepted yet or the accepted one is being updated.
 		mc.oldest = gc
-	case gc.CreationTimestamp.Time.Equal(mc.oldest.CreationTimestamp.Time) && gc.Name < mc.oldest.Name:
-		// The first one in alphabetical order is considered oldest/accepted.
+	case isOlder(gc, mc.oldest):
 		mc.oldest = gc
 	}
 }
 
-func (mc *managedClasses) remove(gc *gwapiv1b1.GatewayClass) {
+func (mc *managedClasses) remove(gc *gwapiv1.GatewayClass) {
 	// First remove gc from matched.
 	delete(mc.matched, gc.Name)
 
 	// If gc is the oldest gatewayclass, remove it.
 	if mc.oldest != nil && mc.oldest.Name == gc.Name {
-		mc.oldest = new(gwapiv1b1.GatewayClass)
+		mc.oldest = new(gwapiv1.GatewayClass)
 		// Set the new oldest from the matched gatewayclasses.
-		for _, matchedGC := range mc.matched {
-			if matchedGC.CreationTimestamp.Time.Before(mc.oldest.CreationTimestamp.Time) ||
-				(matchedGC.CreationTimestamp.Time.Equal(mc.oldest.CreationTimestamp.Time) &&
-					matchedGC.Name < mc.oldest.Name) {
+		for name := range mc.matched {
+			matchedGC := mc.matched[name]
+			if mc.oldest.Name == "" || isOlder(&matchedGC, mc.oldest) {
 				mc.oldest = &matchedGC
-				return
 			}
 		}
 	}
 }
 
-func (mc *managedClasses) accepted() *gwapiv1b1.GatewayClass {
+// isOlder returns true if a was created before b. When both were created at the
+// same time, the first one in alphabetical order is considered oldest.
+func isOlder(a, b *gwapiv1.GatewayClass) bool {
+	if a.CreationTimestamp.Time.Equal(b.CreationTimestamp.Time) {
+		return a.Name < b.Name
+	}
+	return a.CreationTimestamp.Time.Before(b.CreationTimestamp.Time)
+}
+
+func (mc *managedClasses) accepted() *gwapiv1.GatewayClass {
 	return mc.oldest
 }
 
-func (mc *managedClasses) notAccepted() []gwapiv1b1.GatewayClass {
-	var res []gwapiv1b1.GatewayClass
+func (mc *managedClasses) notAccepted() []gwapiv1.GatewayClass {
+	var res []gwapiv1.GatewayClass
 	for _, gc := range mc.matched {
 		// Skip the oldest one since it will be accepted.
 		if gc.Name != mc.oldest.Name {
@@ -77,10 +218,287 @@ func (mc *managedClasses) notAccepted() []gwapiv1b1.GatewayClass {
 	return res
 }
 
-func (mc *managedClasses) all() []gwapiv1b1.GatewayClass {
-	res := []gwapiv1b1.GatewayClass{*mc.accepted()}
+func (mc *managedClasses) all() []gwapiv1.GatewayClass {
+	var res []gwapiv1.GatewayClass
+	if accepted := mc.accepted(); accepted.Name != "" {
+		res = append(res, *accepted)
+	}
 	na := mc.notAccepted()
 	res = append(res, na...)
 
 	return res
 }
+
+// incompatibleCRDs returns an error describing the installed GatewayClass and Gateway
+// CRDs that can't be reconciled by the manager, or nil if both are compatible. The
+// controllers of incompatible route CRDs are left unregistered instead.
+func (s *ObjectStore) incompatibleCRDs() error {
+	var errs []error
+	for _, kind := range []gwapiv1.Kind{"GatewayClass", "Gateway"} {
+		crd, ok := s.crds[kind]
+		if !ok {
+			continue
+		}
+		if err := crd.Compatible(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// apiVersion returns the API version used to read and write objects of kind,
+// defaulting to v1.
+func (s *ObjectStore) apiVersion(kind gwapiv1.Kind) string {
+	if v, ok := s.apiVersions[kind]; ok && v != "" {
+		return v
+	}
+	return gatewayapi.V1
+}
+
+// sendToProcessor notifies the processor of a change to obj. It records when the
+// processor was first notified for the processor queue latency, and the span of
+// ctx so the processor span links to the reconciles that triggered it. The store
+// must not be locked by the caller. The notification is dropped once ctx is done.
+func (s *ObjectStore) sendToProcessor(ctx context.Context, ch chan<- event.GenericEvent, obj client.Object) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("sent to processor", trace.WithAttributes(
+		tracing.AttrNamespace.String(obj.GetNamespace()), tracing.AttrName.String(obj.GetName())))
+
+	s.mu.Lock()
+	nsName := client.ObjectKeyFromObject(obj)
+	pending, ok := s.enqueued[nsName]
+	if !ok {
+		pending = &pendingRequest{enqueued: time.Now()}
+		s.enqueued[nsName] = pending
+	}
+	if sc := span.SpanContext(); sc.IsValid() {
+		pending.links = append(pending.links, trace.Link{SpanContext: sc})
+	}
+	s.mu.Unlock()
+
+	select {
+	case ch <- event.GenericEvent{Object: obj}:
+	case <-ctx.Done():
+	}
+}
+
+// sendGatewaysToProcessor notifies the processor of a change to the configuration of the
+// named gateways. The store must not be locked by the caller.
+func (s *ObjectStore) sendGatewaysToProcessor(ctx context.Context, ch chan<- event.GenericEvent, keys []types.NamespacedName) {
+	var gateways []gwapiv1.Gateway
+	s.mu.Lock()
+	for _, key := range keys {
+		if gw, ok := s.gateways[key]; ok {
+			gateways = append(gateways, gw)
+		}
+	}
+	s.mu.Unlock()
+
+	for i := range gateways {
+		s.sendToProcessor(ctx, ch, &gateways[i])
+	}
+}
+
+// sendGatewayToRouteReconcilers notifies the route reconcilers that gw was stored or
+// removed, so they store the routes attached to it. The store must not be locked by
+// the caller.
+func (s *ObjectStore) sendGatewayToRouteReconcilers(ctx context.Context, gw *gwapiv1.Gateway) {
+	var chans []chan event.GenericEvent
+	s.mu.Lock()
+	for _, ch := range s.routeChans {
+		chans = append(chans, ch)
+	}
+	s.mu.Unlock()
+
+	for _, ch := range chans {
+		select {
+		case ch <- event.GenericEvent{Object: gw}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// routeAttached returns true if a parentRef of route references a managed gateway.
+// The store must be locked by the caller.
+func (s *ObjectStore) routeAttached(route client.Object) bool {
+	for _, ref := range routeParentRefs(route) {
+		if key, ok := parentRefGateway(route, ref); ok {
+			if _, ok := s.gateways[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GatewaysUsingConfig returns the managed gateways of the gatewayclasses configured by
+// the named GatewayClassConfig, sorted by name. The store must not be locked by the caller.
+func (s *ObjectStore) GatewaysUsingConfig(cfg *model.ManagerConfig, nsName types.NamespacedName) []types.NamespacedName {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.gatewaysUsingClassConfig(cfg, nsName)
+}
+
+// gatewaysUsingClassConfig returns the managed gateways of the gatewayclasses configured
+// by the named GatewayClassConfig, sorted by name. The store must be locked by the caller.
+func (s *ObjectStore) gatewaysUsingClassConfig(cfg *model.ManagerConfig, nsName types.NamespacedName) []types.NamespacedName {
+	classes := map[string]bool{}
+	for _, gc := range s.gatewayclasses.matched() {
+		if name, ok := gatewayClassConfigName(cfg, &gc); ok && name == nsName {
+			classes[gc.Name] = true
+		}
+	}
+
+	var res []types.NamespacedName
+	for key, gw := range s.gateways {
+		if classes[gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName)] {
+			res = append(res, key)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String() < res[j].String() })
+
+	return res
+}
+
+// gatewaysUsingGatewayConfig returns the managed gateways whose parameters annotation
+// names the GatewayConfig, sorted by name. The store must be locked by the caller.
+func (s *ObjectStore) gatewaysUsingGatewayConfig(nsName types.NamespacedName) []types.NamespacedName {
+	var res []types.NamespacedName
+	for key, gw := range s.gateways {
+		if key.Namespace == nsName.Namespace && gw.Annotations[provisioner.AnnotationParameters] == nsName.Name {
+			res = append(res, key)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String() < res[j].String() })
+
+	return res
+}
+
+// gatewaysUsingService returns the managed gateways exposed by the provisioned Service:
+// its gateway, or the gateways of its gatewayclass if the Service is shared by merged
+// gateways. The gateways are sorted by name. The store must be locked by the caller.
+func (s *ObjectStore) gatewaysUsingService(svc *corev1.Service) []types.NamespacedName {
+	var res []types.NamespacedName
+	if name, ok := svc.Labels[provisioner.LabelGatewayName]; ok {
+		key := types.NamespacedName{Namespace: svc.Namespace, Name: name}
+		if _, ok := s.gateways[key]; ok {
+			res = append(res, key)
+		}
+		return res
+	}
+
+	className, ok := svc.Labels[provisioner.LabelGatewayClassName]
+	if !ok {
+		return nil
+	}
+	for key, gw := range s.gateways {
+		if gatewayapi.ObjectNameToStr(gw.Spec.GatewayClassName) == className {
+			res = append(res, key)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].String() < res[j].String() })
+
+	return res
+}
+
+// StoreSnapshot is a copy of the contents of the object store.
+type StoreSnapshot struct {
+	// AcceptedGatewayClasses are the names of the accepted gatewayclasses by controllerName.
+	AcceptedGatewayClasses map[string]string      `json:"acceptedGatewayClasses"`
+	GatewayClasses         []gwapiv1.GatewayClass `json:"gatewayClasses"`
+	Gateways               []gwapiv1.Gateway      `json:"gateways"`
+	// Routes are the routes by kind.
+	Routes              map[gwapiv1.Kind][]client.Object `json:"routes"`
+	GatewayClassConfigs []cfgv1b1.GatewayClassConfig     `json:"gatewayClassConfigs"`
+	GatewayConfigs      []cfgv1b1.GatewayConfig          `json:"gatewayConfigs"`
+	CRDs                []gatewayapi.CRD                 `json:"crds"`
+	// APIVersions are the API versions used to read and write each kind.
+	APIVersions map[gwapiv1.Kind]string `json:"apiVersions"`
+	// Pending are the requests sent to the processor but not yet processed.
+	Pending []types.NamespacedName `json:"pending"`
+}
+
+// Snapshot returns a copy of the contents of the store, sorted by name. Managed fields
+// are omitted. The store must not be locked by the caller.
+func (s *ObjectStore) Snapshot() *StoreSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := &StoreSnapshot{
+		AcceptedGatewayClasses: map[string]string{},
+		GatewayClasses:         []gwapiv1.GatewayClass{},
+		Gateways:               []gwapiv1.Gateway{},
+		Routes:                 map[gwapiv1.Kind][]client.Object{},
+		GatewayClassConfigs:    []cfgv1b1.GatewayClassConfig{},
+		GatewayConfigs:         []cfgv1b1.GatewayConfig{},
+		CRDs:                   []gatewayapi.CRD{},
+		APIVersions:            map[gwapiv1.Kind]string{},
+		Pending:                []types.NamespacedName{},
+	}
+	for _, gc := range s.gatewayclasses.accepted() {
+		snap.AcceptedGatewayClasses[string(gc.Spec.ControllerName)] = gc.Name
+	}
+	for _, gc := range s.gatewayclasses.matched() {
+		gc := gc.DeepCopy()
+		gc.ManagedFields = nil
+		snap.GatewayClasses = append(snap.GatewayClasses, *gc)
+	}
+	sort.Slice(snap.GatewayClasses, func(i, j int) bool {
+		return snap.GatewayClasses[i].Name < snap.GatewayClasses[j].Name
+	})
+	for _, gw := range s.gateways {
+		gw := gw.DeepCopy()
+		gw.ManagedFields = nil
+		snap.Gateways = append(snap.Gateways, *gw)
+	}
+	sort.Slice(snap.Gateways, func(i, j int) bool {
+		return objectKeyLess(&snap.Gateways[i], &snap.Gateways[j])
+	})
+	for kind, routes := range s.routes {
+		res := []client.Object{}
+		for _, route := range routes {
+			route := route.DeepCopyObject().(client.Object)
+			route.SetManagedFields(nil)
+			res = append(res, route)
+		}
+		sort.Slice(res, func(i, j int) bool { return objectKeyLess(res[i], res[j]) })
+		snap.Routes[kind] = res
+	}
+	for _, gcc := range s.gatewayClassConfigs {
+		gcc := gcc.DeepCopy()
+		gcc.ManagedFields = nil
+		snap.GatewayClassConfigs = append(snap.GatewayClassConfigs, *gcc)
+	}
+	sort.Slice(snap.GatewayClassConfigs, func(i, j int) bool {
+		return objectKeyLess(&snap.GatewayClassConfigs[i], &snap.GatewayClassConfigs[j])
+	})
+	for _, gwc := range s.gatewayConfigs {
+		gwc := gwc.DeepCopy()
+		gwc.ManagedFields = nil
+		snap.GatewayConfigs = append(snap.GatewayConfigs, *gwc)
+	}
+	sort.Slice(snap.GatewayConfigs, func(i, j int) bool {
+		return objectKeyLess(&snap.GatewayConfigs[i], &snap.GatewayConfigs[j])
+	})
+	for _, crd := range s.crds {
+		snap.CRDs = append(snap.CRDs, crd)
+	}
+	sort.Slice(snap.CRDs, func(i, j int) bool { return snap.CRDs[i].Name < snap.CRDs[j].Name })
+	for kind := range s.apiVersions {
+		snap.APIVersions[kind] = s.apiVersion(kind)
+	}
+	for nsName := range s.enqueued {
+		snap.Pending = append(snap.Pending, nsName)
+	}
+	sort.Slice(snap.Pending, func(i, j int) bool { return snap.Pending[i].String() < snap.Pending[j].String() })
+
+	return snap
+}
+
+// objectKeyLess returns true if the namespace/name key of a sorts before that of b.
+func objectKeyLess(a, b client.Object) bool {
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
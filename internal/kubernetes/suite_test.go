@@ -1,3 +1,5 @@
+//go:build envtest
+
 /*
 Copyright 2023.
 
@@ -17,29 +19,68 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 
 	configv1alpha1 "solo.io/sample-gateway-manager/api/v1alpha1"
+	configv1beta1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/events"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/status"
+	"solo.io/sample-gateway-manager/internal/xds"
 	//+kubebuilder:scaffold:imports
 )
 
 // These tests use Ginkgo (BDD-style Go testing framework). Refer to
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
+const (
+	testControllerName       = "sample.io/gateway-manager"
+	testSecondControllerName = "sample.io/second-gateway-manager"
+	// testDefaultGatewayClassConfig is the default GatewayClassConfig of the second controller.
+	testDefaultGatewayClassConfig = "second-default"
+	// testProxyImage is the proxy image of the provisioned Deployments, whose pods don't
+	// run in envtest.
+	testProxyImage = "docker.io/envoyproxy/envoy:v1.28.0"
+	// testXDSAddress is the address of the xDS server in the bootstrap of the proxies.
+	testXDSAddress = "xds.sample-gateway-manager.svc:18000"
+
+	timeout  = 10 * time.Second
+	interval = 250 * time.Millisecond
+)
+
 var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var store *ObjectStore
+var cancel context.CancelFunc
+var spanRecorder *tracetest.SpanRecorder
+var xdsCA *xds.CA
+var processor *Processor
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -50,9 +91,17 @@ func TestAPIs(t *testing.T) {
 var _ = BeforeSuite(func() {
 	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
 
+	// The suite is built with the envtest tag by "make test", which sets the assets.
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		Fail("KUBEBUILDER_ASSETS is not set; run the suite with \"make test\"")
+	}
+
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "config", "crd", "bases"),
+			filepath.Join("..", "..", "test", "crds", "gateway-api"),
+		},
 		ErrorIfCRDPathMissing: true,
 	}
 
@@ -64,6 +113,14 @@ var _ = BeforeSuite(func() {
 
 	err = configv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = configv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = apiextensionsv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = gwapiv1a2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = gwapiv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	//+kubebuilder:scaffold:scheme
 
@@ -71,10 +128,144 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	// Record the spans of the manager in memory.
+	spanRecorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+
+	By("starting the manager")
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme.Scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	featureGates := model.NewFeatureGates()
+	Expect(featureGates.SetFromMap(map[string]bool{string(model.TCPRoute): true})).To(Succeed())
+	mgrCfg := &model.ManagerConfig{
+		Controllers: []model.ControllerConfig{
+			{Name: testControllerName},
+			{
+				Name:                      testSecondControllerName,
+				DefaultGatewayClassConfig: &types.NamespacedName{Namespace: "default", Name: testDefaultGatewayClassConfig},
+			},
+		},
+		FeatureGates:        featureGates,
+		ProxyImage:          testProxyImage,
+		XDSAddress:          testXDSAddress,
+		CertificateValidity: time.Hour,
+	}
+	procChan := make(chan event.GenericEvent)
+	store = NewObjectStore()
+
+	recorder := events.NewRecorder(mgr.GetEventRecorderFor("sample-gateway-manager"), events.DefaultTTL)
+	statusUpdater := status.NewUpdater(mgr.GetClient(), mgr.GetLogger(), recorder)
+	Expect(mgr.Add(statusUpdater)).To(Succeed())
+
+	err = (&GatewayClassConfigReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&GatewayConfigReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&ServiceReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&GatewayClassReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&GatewayReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = (&RouteReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Config:      mgrCfg,
+		Kind:        "TCPRoute",
+		ObjectStore: store,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	// The TLSRoute controller is registered by the CRD reconciler to test discovery.
+	err = (&CRDReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		Kinds:         []gwapiv1.Kind{"TLSRoute"},
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+		Setup: func(crd gatewayapi.CRD) error {
+			return (&RouteReconciler{
+				Client:      mgr.GetClient(),
+				Scheme:      mgr.GetScheme(),
+				Config:      mgrCfg,
+				Kind:        crd.Kind,
+				ObjectStore: store,
+			}).SetupWithManager(mgr)
+		},
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	xdsCA, err = LoadCA(context.Background(), mgr.GetClient(), mgr.GetAPIReader(),
+		types.NamespacedName{Namespace: "default", Name: "xds-ca"})
+	Expect(err).NotTo(HaveOccurred())
+
+	processor = &Processor{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		ObjectStore:   store,
+		UpdateChan:    procChan,
+		StatusUpdater: statusUpdater,
+		CA:            xdsCA,
+		APIReader:     mgr.GetAPIReader(),
+	}
+	err = processor.SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(ctx)).To(Succeed())
+	}()
 })
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	if testEnv == nil {
+		return
+	}
+	cancel()
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
@@ -0,0 +1,235 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"solo.io/sample-gateway-manager/internal/provisioner"
+	"solo.io/sample-gateway-manager/internal/xds"
+)
+
+//+kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get;create;update;patch;delete
+
+// caCertKey is the key of the CA certificate in the Secrets of the xDS certificates.
+const caCertKey = "ca.crt"
+
+// LoadCA returns the xDS CA stored in the Secret named nsName. The Secret is created with
+// a new CA if it doesn't exist. The Secret is read with reader, so the CA can be loaded
+// before the cache of c is started.
+func LoadCA(ctx context.Context, c client.Client, reader client.Reader, nsName types.NamespacedName) (*xds.CA, error) {
+	secret := new(corev1.Secret)
+	err := reader.Get(ctx, nsName, secret)
+	if apierrors.IsNotFound(err) {
+		ca, err := xds.NewCA(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: nsName.Namespace, Name: nsName.Name},
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       ca.CertPEM(),
+				corev1.TLSPrivateKeyKey: ca.KeyPEM(),
+			},
+		}
+		if err = c.Create(ctx, secret); err == nil {
+			return ca, nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create the xDS CA secret %s: %w", nsName, err)
+		}
+		// Another replica created the CA first.
+		err = reader.Get(ctx, nsName, secret)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the xDS CA secret %s: %w", nsName, err)
+	}
+
+	ca, err := xds.LoadCA(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid xDS CA secret %s: %w", nsName, err)
+	}
+	return ca, nil
+}
+
+// serverCertificateRetryPeriod is the time the issuance of the xDS server certificate is
+// retried after when it fails.
+const serverCertificateRetryPeriod = 10 * time.Second
+
+// ServerCertificateIssuer keeps the certificate of the xDS server in a Secret, for the
+// xDS server to mount. The certificate is issued by the CA to the host the proxies
+// connect to, which they match against its DNS names, and is renewed once two thirds of
+// its validity elapsed. Only the leader issues it.
+type ServerCertificateIssuer struct {
+	client.Client
+	// Reader reads the Secret, which isn't cached.
+	Reader client.Reader
+	Log    logr.Logger
+	CA     *xds.CA
+	// Secret is the name of the Secret of the certificate.
+	Secret types.NamespacedName
+	// Host is the DNS name of the xDS server.
+	Host     string
+	Validity time.Duration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable so only the leader
+// issues the certificate.
+func (i *ServerCertificateIssuer) NeedLeaderElection() bool {
+	return true
+}
+
+// Start issues the certificate, and renews it until ctx is done.
+func (i *ServerCertificateIssuer) Start(ctx context.Context) error {
+	for {
+		wait := serverCertificateRetryPeriod
+		renewAt, err := i.ensure(ctx, time.Now())
+		if err != nil {
+			i.Log.Error(err, "failed to issue the xDS server certificate", "secret", i.Secret)
+		} else {
+			wait = time.Until(renewAt)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// ensure issues a new certificate into the Secret unless it holds a certificate of the
+// host issued by the CA that isn't due for renewal at now. It returns the time the
+// certificate is renewed.
+func (i *ServerCertificateIssuer) ensure(ctx context.Context, now time.Time) (time.Time, error) {
+	secret := new(corev1.Secret)
+	if err := i.Reader.Get(ctx, i.Secret, secret); err == nil {
+		cert, err := i.CA.VerifyServer(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], i.Host, now)
+		if err == nil && now.Before(cert.RenewAt()) && bytes.Equal(secret.Data[caCertKey], i.CA.CertPEM()) {
+			return cert.RenewAt(), nil
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return time.Time{}, fmt.Errorf("failed to get the xDS server certificate secret %s: %w", i.Secret, err)
+	}
+
+	cert, err := i.CA.IssueServer(i.Host, i.Validity, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to issue the xDS server certificate: %w", err)
+	}
+	secret = &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: i.Secret.Namespace, Name: i.Secret.Name},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert.CertPEM,
+			corev1.TLSPrivateKeyKey: cert.KeyPEM,
+			caCertKey:               i.CA.CertPEM(),
+		},
+	}
+	if err := i.Patch(ctx, secret, client.Apply, fieldOwner, client.ForceOwnership); err != nil {
+		return time.Time{}, fmt.Errorf("failed to apply the xDS server certificate secret %s: %w", i.Secret, err)
+	}
+	i.Log.Info("issued xDS server certificate", "secret", i.Secret, "host", i.Host, "expires", cert.NotAfter)
+
+	return cert.RenewAt(), nil
+}
+
+// proxyXDS returns the connection of the proxy named key to the xDS server, with its
+// client certificate. The certificate of the Secret of the proxy is reused until it is
+// due for renewal, when a new certificate is issued. Only the leader issues
+// certificates, and nil is returned on standby replicas, or if no xDS server address
+// is configured. The object store must not be locked by the caller.
+func (p *Processor) proxyXDS(ctx context.Context, key types.NamespacedName) (*provisioner.XDS, error) {
+	if !p.leading.Load() || p.Config.XDSAddress == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	nodeID := provisioner.NodeID(key)
+	cert, ok := p.certificates[key]
+	if !ok {
+		// The certificate issued before the manager was restarted is kept if it is valid.
+		secret := new(corev1.Secret)
+		if err := p.APIReader.Get(ctx, key, secret); err == nil {
+			cert, _ = p.CA.Verify(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], nodeID, now)
+		} else if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get the xDS certificate of proxy %s: %w", key, err)
+		}
+	}
+	if cert == nil || !now.Before(cert.RenewAt()) {
+		issued, err := p.CA.Issue(nodeID, p.Config.CertificateValidity, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue the xDS certificate of proxy %s: %w", key, err)
+		}
+		logr.FromContextOrDiscard(ctx).Info("issued xDS certificate", "proxy", key, "expires", issued.NotAfter)
+		cert = issued
+	}
+
+	p.mu.Lock()
+	if p.certificates == nil {
+		p.certificates = map[types.NamespacedName]*xds.Certificate{}
+	}
+	p.certificates[key] = cert
+	p.mu.Unlock()
+
+	return &provisioner.XDS{Address: p.Config.XDSAddress, CACert: p.CA.CertPEM(), Certificate: cert}, nil
+}
+
+// ProxyCertificate is the xDS certificate issued to a proxy.
+type ProxyCertificate struct {
+	// Proxy is the name of the resources of the proxy.
+	Proxy     types.NamespacedName `json:"proxy"`
+	NodeID    string               `json:"nodeID"`
+	NotBefore time.Time            `json:"notBefore"`
+	NotAfter  time.Time            `json:"notAfter"`
+	// RenewAt is the time the certificate is renewed.
+	RenewAt time.Time `json:"renewAt"`
+}
+
+// Certificates returns the xDS certificates issued to the proxies, sorted by proxy. Only
+// the leader issues certificates, so it is empty on standby replicas.
+func (p *Processor) Certificates() []ProxyCertificate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	res := []ProxyCertificate{}
+	for key, cert := range p.certificates {
+		res = append(res, ProxyCertificate{
+			Proxy:     key,
+			NodeID:    provisioner.NodeID(key),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			RenewAt:   cert.RenewAt(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Proxy.String() < res[j].Proxy.String() })
+	return res
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics defines the Prometheus metrics of the manager. The metrics are
+// registered on the controller-runtime registry and served by the manager metrics
+// endpoint alongside the default controller-runtime metrics.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "sample_gateway_manager"
+
+// Results of operations recorded by the metrics.
+const (
+	ResultSuccess  = "success"
+	ResultConflict = "conflict"
+	ResultError    = "error"
+)
+
+var (
+	// ManagedResources is the number of managed resources by kind and status of
+	// their Accepted condition.
+	ManagedResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "managed_resources",
+		Help:      "Number of managed Gateway API resources by kind and status of the Accepted condition.",
+	}, []string{"kind", "condition", "status"})
+
+	// TranslationDuration is the time taken by the processor to translate the
+	// object store.
+	TranslationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "translation_duration_seconds",
+		Help:      "Time taken by the processor to translate the managed resources.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	})
+
+	// TranslationErrors is the number of failed translations.
+	TranslationErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "translation_errors_total",
+		Help:      "Number of translations of the managed resources that failed.",
+	})
+
+	// ProcessorQueueLatency is the time between a reconciler notifying the processor
+	// of a change and the processor handling it.
+	ProcessorQueueLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "processor_queue_latency_seconds",
+		Help:      "Time between a reconciler notifying the processor of a change and the processor handling it.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	})
+
+	// StatusUpdates is the number of status writes by kind and result.
+	StatusUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "status_updates_total",
+		Help:      "Number of status updates by kind and result (success, conflict or error).",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ManagedResources,
+		TranslationDuration,
+		TranslationErrors,
+		ProcessorQueueLatency,
+		StatusUpdates,
+	)
+}
+
+// Result returns the result label of an operation that returned err.
+func Result(err error) string {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case apierrors.IsConflict(err):
+		return ResultConflict
+	default:
+		return ResultError
+	}
+}
+
+// RecordStatusUpdate records a status update of kind that returned err.
+func RecordStatusUpdate(kind string, err error) {
+	StatusUpdates.WithLabelValues(kind, Result(err)).Inc()
+}
+
+// RecordTranslation records a translation that started at start and returned err.
+func RecordTranslation(start time.Time, err error) {
+	TranslationDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		TranslationErrors.Inc()
+	}
+}
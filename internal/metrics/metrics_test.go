@@ -0,0 +1,67 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResult(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "gateways"}, "test", errors.New("conflict"))
+	testCases := map[string]struct {
+		err  error
+		want string
+	}{
+		"success":  {err: nil, want: ResultSuccess},
+		"conflict": {err: conflict, want: ResultConflict},
+		"error":    {err: errors.New("failed"), want: ResultError},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := Result(tc.err); got != tc.want {
+				t.Errorf("expected result %q, found %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRecordStatusUpdate(t *testing.T) {
+	before := testutil.ToFloat64(StatusUpdates.WithLabelValues("Gateway", ResultConflict))
+	RecordStatusUpdate("Gateway", apierrors.NewConflict(schema.GroupResource{Resource: "gateways"}, "test", errors.New("conflict")))
+	if got := testutil.ToFloat64(StatusUpdates.WithLabelValues("Gateway", ResultConflict)); got != before+1 {
+		t.Errorf("expected %v conflicts, found %v", before+1, got)
+	}
+}
+
+func TestRecordTranslation(t *testing.T) {
+	before := testutil.ToFloat64(TranslationErrors)
+	RecordTranslation(time.Now(), nil)
+	RecordTranslation(time.Now(), errors.New("failed"))
+	if got := testutil.ToFloat64(TranslationErrors); got != before+1 {
+		t.Errorf("expected %v translation errors, found %v", before+1, got)
+	}
+	if got := testutil.CollectAndCount(TranslationDuration); got != 1 {
+		t.Errorf("expected a single translation duration series, found %d", got)
+	}
+}
@@ -16,17 +16,66 @@ limitations under the License.
 
 package model
 
-import gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
 
 type ManagerConfig struct {
-	ControllerName string
+	// Controllers are the controllers served by the manager.
+	Controllers []ControllerConfig
+
+	// FeatureGates are the features enabled in the manager.
+	FeatureGates *FeatureGates
+
+	// RouteKinds are the route kinds reconciled by the manager.
+	RouteKinds []gwapiv1.Kind
+
+	// ProxyImage is the container image of the proxies whose GatewayClassConfig
+	// doesn't set one.
+	ProxyImage string
+
+	// XDSAddress is the host:port the proxies connect to the xDS server at. The proxies
+	// aren't bootstrapped if it is empty.
+	XDSAddress string
+
+	// CertificateValidity is the validity of the xDS certificates issued to the proxies.
+	CertificateValidity time.Duration
+}
+
+// ControllerConfig is the configuration of a controller served by the manager.
+type ControllerConfig struct {
+	// Name is the controllerName of the GatewayClasses managed by the controller.
+	Name string
+
+	// DefaultGatewayClassConfig is the GatewayClassConfig of the managed GatewayClasses
+	// without a parametersRef, or nil if they have none.
+	DefaultGatewayClassConfig *types.NamespacedName
+}
+
+// Controller returns the configuration of the named controller, or nil if the manager
+// doesn't serve it.
+func (c *ManagerConfig) Controller(name gwapiv1.GatewayController) *ControllerConfig {
+	for i := range c.Controllers {
+		if c.Controllers[i].Name == string(name) {
+			return &c.Controllers[i]
+		}
+	}
+	return nil
+}
+
+// Manages returns true if the manager serves the controller of gc.
+func (c *ManagerConfig) Manages(gc *gwapiv1.GatewayClass) bool {
+	return c.Controller(gc.Spec.ControllerName) != nil
 }
 
 type ManagedClasses struct {
 	// Matched stores all GatewayClass objects with a controllerName.
-	Matched []*gwapiv1b1.GatewayClass
+	Matched []*gwapiv1.GatewayClass
 
 	// Oldest stores the first GatewayClass encountered with matching
 	// controllerName.
-	Oldest *gwapiv1b1.GatewayClass
+	Oldest *gwapiv1.GatewayClass
 }
@@ -0,0 +1,179 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature is the name of a feature that can be enabled or disabled with a feature gate.
+type Feature string
+
+// Stage is the maturity of a feature.
+type Stage string
+
+const (
+	// Alpha features are disabled by default and may change or be removed at any time.
+	Alpha Stage = "ALPHA"
+	// Beta features are enabled by default and can still be disabled.
+	Beta Stage = "BETA"
+	// GA features are always enabled. Their gate will be removed in a future release.
+	GA Stage = "GA"
+)
+
+const (
+	// TCPRoute enables the TCPRoute reconciler. Requires the experimental TCPRoute CRD.
+	TCPRoute Feature = "TCPRoute"
+	// TLSRoute enables the TLSRoute reconciler. Requires the experimental TLSRoute CRD.
+	TLSRoute Feature = "TLSRoute"
+	// UDPRoute enables the UDPRoute reconciler. Requires the experimental UDPRoute CRD.
+	UDPRoute Feature = "UDPRoute"
+	// GRPCRoute enables the GRPCRoute reconciler. Requires the experimental GRPCRoute CRD.
+	GRPCRoute Feature = "GRPCRoute"
+)
+
+// FeatureSpec describes the stage and default state of a feature.
+type FeatureSpec struct {
+	Default bool
+	Stage   Stage
+}
+
+// defaultFeatureGates are all the features known to the manager.
+var defaultFeatureGates = map[Feature]FeatureSpec{
+	TCPRoute:  {Default: false, Stage: Alpha},
+	TLSRoute:  {Default: false, Stage: Alpha},
+	UDPRoute:  {Default: false, Stage: Alpha},
+	GRPCRoute: {Default: false, Stage: Alpha},
+}
+
+// FeatureGates tracks the features enabled or disabled in the manager. A nil
+// FeatureGates reports the default state of every feature.
+type FeatureGates struct {
+	mu      sync.RWMutex
+	known   map[Feature]FeatureSpec
+	enabled map[Feature]bool
+}
+
+// NewFeatureGates returns FeatureGates with all known features in their default state.
+func NewFeatureGates() *FeatureGates {
+	return &FeatureGates{
+		known:   defaultFeatureGates,
+		enabled: map[Feature]bool{},
+	}
+}
+
+// SetFromMap sets the state of the features in m. An error is returned if a feature
+// is unknown or if a GA feature is disabled, in which case no feature is set.
+func (fg *FeatureGates) SetFromMap(m map[string]bool) error {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	for name, enabled := range m {
+		spec, ok := fg.known[Feature(name)]
+		if !ok {
+			return fmt.Errorf("unrecognized feature gate: %s", name)
+		}
+		if spec.Stage == GA && !enabled {
+			return fmt.Errorf("cannot disable feature gate %s: feature is GA", name)
+		}
+	}
+	for name, enabled := range m {
+		fg.enabled[Feature(name)] = enabled
+	}
+
+	return nil
+}
+
+// Set implements flag.Value. It parses a comma-separated list of feature=bool pairs.
+func (fg *FeatureGates) Set(value string) error {
+	m := map[string]bool{}
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		name, v, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("missing bool value for feature gate %s", s)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid value of feature gate %s=%s: %w", name, v, err)
+		}
+		m[strings.TrimSpace(name)] = enabled
+	}
+
+	return fg.SetFromMap(m)
+}
+
+// String implements flag.Value. It returns the explicitly set features as a sorted,
+// comma-separated list of feature=bool pairs.
+func (fg *FeatureGates) String() string {
+	if fg == nil {
+		return ""
+	}
+
+	var pairs []string
+	for name, enabled := range fg.Overrides() {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Overrides returns the features that were explicitly set.
+func (fg *FeatureGates) Overrides() map[string]bool {
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+
+	res := make(map[string]bool, len(fg.enabled))
+	for f, enabled := range fg.enabled {
+		res[string(f)] = enabled
+	}
+	return res
+}
+
+// Enabled returns true if the feature f is enabled.
+func (fg *FeatureGates) Enabled(f Feature) bool {
+	if fg == nil {
+		return defaultFeatureGates[f].Default
+	}
+
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+
+	if enabled, ok := fg.enabled[f]; ok {
+		return enabled
+	}
+	return fg.known[f].Default
+}
+
+// KnownFeatures returns a sorted description of all known features for use in help text.
+func (fg *FeatureGates) KnownFeatures() []string {
+	res := make([]string, 0, len(fg.known))
+	for f, spec := range fg.known {
+		res = append(res, fmt.Sprintf("%s=true|false (%s - default=%t)", f, spec.Stage, spec.Default))
+	}
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import "testing"
+
+func TestFeatureGates(t *testing.T) {
+	testCases := []struct {
+		name    string
+		value   string
+		want    map[Feature]bool
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			value: "",
+			want:  map[Feature]bool{TCPRoute: false, TLSRoute: false, UDPRoute: false, GRPCRoute: false},
+		},
+		{
+			name:  "enable alpha features",
+			value: "TCPRoute=true, GRPCRoute=true",
+			want:  map[Feature]bool{TCPRoute: true, TLSRoute: false, UDPRoute: false, GRPCRoute: true},
+		},
+		{
+			name:    "unknown feature",
+			value:   "Unknown=true",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			value:   "TCPRoute",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			value:   "TCPRoute=yes",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fg := NewFeatureGates()
+			err := fg.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for f, want := range tc.want {
+				if got := fg.Enabled(f); got != want {
+					t.Errorf("expected %s enabled to be %t, found %t", f, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFeatureGatesGA(t *testing.T) {
+	fg := NewFeatureGates()
+	fg.known = map[Feature]FeatureSpec{"Stable": {Default: true, Stage: GA}}
+
+	if err := fg.SetFromMap(map[string]bool{"Stable": false}); err == nil {
+		t.Error("expected an error disabling a GA feature")
+	}
+	if !fg.Enabled("Stable") {
+		t.Error("expected GA feature to be enabled")
+	}
+}
+
+func TestNilFeatureGates(t *testing.T) {
+	var fg *FeatureGates
+	if fg.Enabled(TCPRoute) {
+		t.Errorf("expected %s to be disabled by default", TCPRoute)
+	}
+}
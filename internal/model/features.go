@@ -0,0 +1,41 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import "sort"
+
+// SupportedFeature is a Gateway API feature supported by the manager. Names match
+// the features of the Gateway API conformance suite.
+type SupportedFeature string
+
+const (
+	SupportGateway SupportedFeature = "Gateway"
+)
+
+// supportedFeatures are the features implemented by the manager. Route kinds, and
+// the features they provide, are only added once the manager translates them.
+var supportedFeatures = []SupportedFeature{
+	SupportGateway,
+}
+
+// SupportedFeatures returns the sorted list of features supported by the manager.
+func SupportedFeatures() []SupportedFeature {
+	res := append([]SupportedFeature(nil), supportedFeatures...)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+
+	return res
+}
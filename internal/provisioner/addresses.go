@@ -0,0 +1,128 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// UnsupportedAddressError is returned by NewInfra when an address requested by the
+// Gateway can't be assigned to its Service.
+type UnsupportedAddressError struct {
+	Message string
+}
+
+func (e *UnsupportedAddressError) Error() string {
+	return e.Message
+}
+
+// setAddresses sets the IPs and hostnames requested by the addresses of the Gateway.
+// The IPs are assigned to the Service: a LoadBalancer Service requests a single IP from
+// the load balancer, and the IPs of other Services are external IPs. The IPs of a
+// Gateway merged onto a shared proxy can't be requested.
+func (i *Infra) setAddresses() error {
+	for idx, addr := range i.Gateway.Spec.Addresses {
+		addrType := gwapiv1.IPAddressType
+		if addr.Type != nil {
+			addrType = *addr.Type
+		}
+		switch addrType {
+		case gwapiv1.IPAddressType:
+			ip := net.ParseIP(addr.Value)
+			if ip == nil {
+				return &UnsupportedAddressError{
+					Message: fmt.Sprintf("spec.addresses[%d]: %q is not an IP address", idx, addr.Value),
+				}
+			}
+			i.IPs = append(i.IPs, ip.String())
+		case gwapiv1.HostnameAddressType:
+			i.Hostnames = append(i.Hostnames, addr.Value)
+		default:
+			return &UnsupportedAddressError{
+				Message: fmt.Sprintf("spec.addresses[%d]: address type %s is not supported, only %s and %s are",
+					idx, addrType, gwapiv1.IPAddressType, gwapiv1.HostnameAddressType),
+			}
+		}
+	}
+
+	if i.Config.MergeGateways != nil && len(i.IPs) > 0 {
+		return &UnsupportedAddressError{
+			Message: fmt.Sprintf("%s addresses can't be requested by a Gateway merged onto a shared proxy",
+				gwapiv1.IPAddressType),
+		}
+	}
+	if *i.Config.Service.Type == corev1.ServiceTypeLoadBalancer && len(i.IPs) > 1 {
+		return &UnsupportedAddressError{
+			Message: fmt.Sprintf("a %s Service is assigned a single %s, found %d",
+				corev1.ServiceTypeLoadBalancer, gwapiv1.IPAddressType, len(i.IPs)),
+		}
+	}
+
+	return nil
+}
+
+// Addresses returns the addresses assigned to svc, the Service of the Gateway, followed
+// by the requested hostnames, and whether svc is assigned an address and all requested
+// IPs. svc is nil if the Service doesn't exist yet.
+func (i *Infra) Addresses(svc *corev1.Service) ([]gwapiv1.GatewayStatusAddress, bool) {
+	var addrs []gwapiv1.GatewayStatusAddress
+	assigned := map[string]bool{}
+	addIP := func(ip string) {
+		if ip != "" && ip != corev1.ClusterIPNone && !assigned[ip] {
+			assigned[ip] = true
+			addrs = append(addrs, statusAddress(gwapiv1.IPAddressType, ip))
+		}
+	}
+
+	if svc != nil {
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			for _, ingress := range svc.Status.LoadBalancer.Ingress {
+				addIP(ingress.IP)
+				if ingress.Hostname != "" {
+					addrs = append(addrs, statusAddress(gwapiv1.HostnameAddressType, ingress.Hostname))
+				}
+			}
+		} else {
+			for _, ip := range svc.Spec.ExternalIPs {
+				addIP(ip)
+			}
+			if len(svc.Spec.ExternalIPs) == 0 {
+				addIP(svc.Spec.ClusterIP)
+			}
+		}
+	}
+
+	programmed := len(addrs) > 0
+	for _, ip := range i.IPs {
+		if !assigned[ip] {
+			programmed = false
+		}
+	}
+	for _, hostname := range i.Hostnames {
+		addrs = append(addrs, statusAddress(gwapiv1.HostnameAddressType, hostname))
+	}
+
+	return addrs, programmed
+}
+
+func statusAddress(addrType gwapiv1.AddressType, value string) gwapiv1.GatewayStatusAddress {
+	return gwapiv1.GatewayStatusAddress{Type: &addrType, Value: value}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+func address(addrType gwapiv1.AddressType, value string) gwapiv1.GatewayAddress {
+	return gwapiv1.GatewayAddress{Type: &addrType, Value: value}
+}
+
+func TestNewInfraAddresses(t *testing.T) {
+	clusterIP := corev1.ServiceTypeClusterIP
+	testCases := []struct {
+		name        string
+		serviceType *corev1.ServiceType
+		addresses   []gwapiv1.GatewayAddress
+		wantErr     bool
+		wantLBIP    string
+		wantExtIPs  []string
+	}{
+		{
+			name:      "load balancer IP",
+			addresses: []gwapiv1.GatewayAddress{{Value: "10.0.0.1"}, address(gwapiv1.HostnameAddressType, "gw.example.com")},
+			wantLBIP:  "10.0.0.1",
+		},
+		{
+			name:        "external IPs",
+			serviceType: &clusterIP,
+			addresses:   []gwapiv1.GatewayAddress{address(gwapiv1.IPAddressType, "10.0.0.1"), {Value: "fd00::1"}},
+			wantExtIPs:  []string{"10.0.0.1", "fd00::1"},
+		},
+		{
+			name:      "several load balancer IPs",
+			addresses: []gwapiv1.GatewayAddress{{Value: "10.0.0.1"}, {Value: "10.0.0.2"}},
+			wantErr:   true,
+		},
+		{
+			name:      "malformed IP",
+			addresses: []gwapiv1.GatewayAddress{{Value: "10.0.0.256"}},
+			wantErr:   true,
+		},
+		{
+			name:      "named address",
+			addresses: []gwapiv1.GatewayAddress{address(gwapiv1.NamedAddressType, "reserved")},
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gw := newGateway(nil)
+			gw.Spec.Addresses = tc.addresses
+			class := &cfgv1b1.GatewayClassConfigSpec{Service: &cfgv1b1.ProxyService{Type: tc.serviceType}}
+
+			infra, err := NewInfra(gw, class, nil, testImage)
+			if tc.wantErr {
+				var addrErr *UnsupportedAddressError
+				if !errors.As(err, &addrErr) {
+					t.Errorf("expected an unsupported address error, found %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			svc := infra.Service("v1")
+			if svc.Spec.LoadBalancerIP != tc.wantLBIP {
+				t.Errorf("expected load balancer IP %q, found %q", tc.wantLBIP, svc.Spec.LoadBalancerIP)
+			}
+			if len(svc.Spec.ExternalIPs) != len(tc.wantExtIPs) {
+				t.Errorf("expected external IPs %v, found %v", tc.wantExtIPs, svc.Spec.ExternalIPs)
+			}
+		})
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	gw := newGateway(nil)
+	gw.Spec.Addresses = []gwapiv1.GatewayAddress{{Value: "10.0.0.1"}, address(gwapiv1.HostnameAddressType, "gw.example.com")}
+	infra, err := NewInfra(gw, nil, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if addrs, programmed := infra.Addresses(nil); programmed || len(addrs) != 1 {
+		t.Errorf("expected only the requested hostname without a Service, found %v and programmed %t", addrs, programmed)
+	}
+
+	svc := infra.Service("v1")
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.2"}}
+	if _, programmed := infra.Addresses(svc); programmed {
+		t.Errorf("expected the gateway not to be programmed until the requested IP is assigned")
+	}
+
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}, {Hostname: "lb.example.com"}}
+	addrs, programmed := infra.Addresses(svc)
+	if !programmed {
+		t.Errorf("expected the gateway to be programmed once the requested IP is assigned")
+	}
+	var values []string
+	for _, addr := range addrs {
+		values = append(values, string(*addr.Type)+"/"+addr.Value)
+	}
+	want := []string{"IPAddress/10.0.0.1", "Hostname/lb.example.com", "Hostname/gw.example.com"}
+	if len(values) != len(want) {
+		t.Fatalf("expected addresses %v, found %v", want, values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("expected addresses %v, found %v", want, values)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"solo.io/sample-gateway-manager/internal/xds"
+)
+
+// AnnotationBootstrapHash is the pod template annotation with the hash of the bootstrap
+// configuration, which rolls the proxies out when it changes.
+const AnnotationBootstrapHash = "gateway.sample.io/bootstrap-hash"
+
+// The bootstrap configuration is mounted from the ConfigMap of the proxy, and the xDS
+// certificates from its Secret, which the proxies watch for renewals.
+const (
+	bootstrapDir      = "/etc/envoy"
+	bootstrapFile     = "envoy.yaml"
+	sdsCertFile       = "xds-certificate.yaml"
+	sdsCAFile         = "xds-ca.yaml"
+	certsDir          = "/var/run/secrets/xds"
+	bootstrapVolume   = "bootstrap"
+	certsVolume       = "xds-certs"
+	xdsClusterName    = "xds"
+	adminClusterName  = "admin"
+	statsName         = "stats"
+	readyPath         = "/ready"
+	xdsCertSecretName = "xds-certificate"
+	xdsCASecretName   = "xds-ca"
+	caCertKey         = "ca.crt"
+)
+
+// XDS is the connection of a proxy to the xDS server of the manager.
+type XDS struct {
+	// Address is the host:port of the xDS server.
+	Address string
+	// CACert is the PEM encoded certificate of the CA the proxy verifies the xDS server
+	// with.
+	CACert []byte
+	// Certificate is the client certificate of the proxy, issued to its node ID.
+	Certificate *xds.Certificate
+}
+
+// NodeID returns the node ID of the proxies named key, "<namespace>/<name>".
+func NodeID(key types.NamespacedName) string {
+	return key.String()
+}
+
+// bootstrapTemplate is the bootstrap configuration of the proxies. The proxies fetch their
+// listeners and clusters from the xDS server over a connection secured with mutual TLS,
+// and only accept a server certificate issued by the CA to the xDS host. The admin API
+// is bound to the loopback interface, and the stats listener forwards the readiness and
+// metrics requests to it.
+var bootstrapTemplate = template.Must(template.New("bootstrap").Parse(`node:
+  id: {{ .NodeID }}
+  cluster: {{ .Cluster }}
+admin:
+  address:
+    socket_address:
+      address: 127.0.0.1
+      port_value: {{ .AdminPort }}
+dynamic_resources:
+  ads_config:
+    api_type: GRPC
+    transport_api_version: V3
+    grpc_services:
+    - envoy_grpc:
+        cluster_name: {{ .XDSCluster }}
+  cds_config:
+    ads: {}
+    resource_api_version: V3
+  lds_config:
+    ads: {}
+    resource_api_version: V3
+static_resources:
+  listeners:
+  - name: {{ .StatsListener }}
+    address:
+      socket_address:
+        address: 0.0.0.0
+        port_value: {{ .StatsPort }}
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.http_connection_manager
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager
+          stat_prefix: {{ .StatsListener }}
+          route_config:
+            virtual_hosts:
+            - name: {{ .StatsListener }}
+              domains: ["*"]
+              routes:
+              - match:
+                  path: {{ .ReadyPath }}
+                route:
+                  cluster: {{ .AdminCluster }}
+              - match:
+                  path: /stats/prometheus
+                route:
+                  cluster: {{ .AdminCluster }}
+          http_filters:
+          - name: envoy.filters.http.router
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.filters.http.router.v3.Router
+  clusters:
+  - name: {{ .XDSCluster }}
+    type: STRICT_DNS
+    connect_timeout: 5s
+    typed_extension_protocol_options:
+      envoy.extensions.upstreams.http.v3.HttpProtocolOptions:
+        "@type": type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions
+        explicit_http_config:
+          http2_protocol_options: {}
+    load_assignment:
+      cluster_name: {{ .XDSCluster }}
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address:
+                address: {{ .XDSHost }}
+                port_value: {{ .XDSPort }}
+    transport_socket:
+      name: envoy.transport_sockets.tls
+      typed_config:
+        "@type": type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext
+        sni: {{ .XDSHost }}
+        common_tls_context:
+          tls_certificate_sds_secret_configs:
+          - name: {{ .CertSecret }}
+            sds_config:
+              path_config_source:
+                path: {{ .BootstrapDir }}/{{ .SDSCertFile }}
+                watched_directory:
+                  path: {{ .CertsDir }}
+          combined_validation_context:
+            default_validation_context:
+              match_typed_subject_alt_names:
+              - san_type: DNS
+                matcher:
+                  exact: {{ .XDSHost }}
+            validation_context_sds_secret_config:
+              name: {{ .CASecret }}
+              sds_config:
+                path_config_source:
+                  path: {{ .BootstrapDir }}/{{ .SDSCAFile }}
+                  watched_directory:
+                    path: {{ .CertsDir }}
+  - name: {{ .AdminCluster }}
+    type: STATIC
+    connect_timeout: 1s
+    load_assignment:
+      cluster_name: {{ .AdminCluster }}
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address:
+                address: 127.0.0.1
+                port_value: {{ .AdminPort }}
+`))
+
+// sdsCertTemplate and sdsCATemplate are the SDS resources of the xDS client certificate
+// and of the CA certificate, read from the mounted Secret.
+var (
+	sdsCertTemplate = template.Must(template.New("certificate").Parse(`resources:
+- "@type": type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret
+  name: {{ .CertSecret }}
+  tls_certificate:
+    certificate_chain:
+      filename: {{ .CertsDir }}/tls.crt
+    private_key:
+      filename: {{ .CertsDir }}/tls.key
+`))
+	sdsCATemplate = template.Must(template.New("ca").Parse(`resources:
+- "@type": type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret
+  name: {{ .CASecret }}
+  validation_context:
+    trusted_ca:
+      filename: {{ .CertsDir }}/ca.crt
+`))
+)
+
+// bootstrapData returns the files of the bootstrap ConfigMap of the proxy.
+func (p *proxy) bootstrapData() map[string]string {
+	host, port, err := net.SplitHostPort(p.xds.Address)
+	if err != nil {
+		// The address is validated with the manager configuration.
+		host, port = p.xds.Address, ""
+	}
+	params := map[string]interface{}{
+		"NodeID":        NodeID(p.key),
+		"Cluster":       p.key.Name,
+		"AdminPort":     AdminPort,
+		"AdminCluster":  adminClusterName,
+		"StatsPort":     StatsPort,
+		"StatsListener": statsName,
+		"ReadyPath":     readyPath,
+		"XDSCluster":    xdsClusterName,
+		"XDSHost":       host,
+		"XDSPort":       port,
+		"CertSecret":    xdsCertSecretName,
+		"CASecret":      xdsCASecretName,
+		"BootstrapDir":  bootstrapDir,
+		"SDSCertFile":   sdsCertFile,
+		"SDSCAFile":     sdsCAFile,
+		"CertsDir":      certsDir,
+	}
+
+	data := map[string]string{}
+	for file, tmpl := range map[string]*template.Template{
+		bootstrapFile: bootstrapTemplate,
+		sdsCertFile:   sdsCertTemplate,
+		sdsCAFile:     sdsCATemplate,
+	} {
+		var buf bytes.Buffer
+		// The templates only reference the parameters above.
+		_ = tmpl.Execute(&buf, params)
+		data[file] = buf.String()
+	}
+	return data
+}
+
+// configMap returns the ConfigMap of the bootstrap configuration of the proxy.
+func (p *proxy) configMap() *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "ConfigMap"},
+		ObjectMeta: p.objectMeta(nil),
+		Data:       p.bootstrapData(),
+	}
+}
+
+// secret returns the Secret of the xDS client certificate of the proxy.
+func (p *proxy) secret() *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Secret"},
+		ObjectMeta: p.objectMeta(nil),
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       p.xds.Certificate.CertPEM,
+			corev1.TLSPrivateKeyKey: p.xds.Certificate.KeyPEM,
+			caCertKey:               p.xds.CACert,
+		},
+	}
+}
+
+// bootstrapHash returns the hash of the bootstrap configuration of the proxy.
+func (p *proxy) bootstrapHash() string {
+	data := p.bootstrapData()
+	h := sha256.New()
+	for _, file := range []string{bootstrapFile, sdsCertFile, sdsCAFile} {
+		fmt.Fprintf(h, "%s\x00%s\x00", file, data[file])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// mountBootstrap mounts the bootstrap configuration and the xDS certificates of the
+// proxy into the proxy container of spec.
+func (p *proxy) mountBootstrap(spec *corev1.PodSpec) {
+	spec.Volumes = append(spec.Volumes,
+		corev1.Volume{
+			Name: bootstrapVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: p.key.Name},
+				},
+			},
+		},
+		corev1.Volume{
+			Name: certsVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: p.key.Name},
+			},
+		},
+	)
+
+	container := &spec.Containers[0]
+	container.Ports = append(container.Ports, corev1.ContainerPort{
+		Name:          statsName,
+		ContainerPort: StatsPort,
+		Protocol:      corev1.ProtocolTCP,
+	})
+	container.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: readyPath, Port: intstr.FromString(statsName)},
+		},
+	}
+	container.VolumeMounts = append(container.VolumeMounts,
+		corev1.VolumeMount{Name: bootstrapVolume, MountPath: bootstrapDir, ReadOnly: true},
+		corev1.VolumeMount{Name: certsVolume, MountPath: certsDir, ReadOnly: true},
+	)
+}
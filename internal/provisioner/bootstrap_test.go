@@ -0,0 +1,162 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"solo.io/sample-gateway-manager/internal/xds"
+)
+
+func TestBootstrapResources(t *testing.T) {
+	now := time.Now()
+	ca, err := xds.NewCA(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := ca.Issue("default/gw-proxy", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	infra, err := NewInfra(newGateway(nil), nil, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	infra.XDS = &XDS{Address: "xds.example.com:18000", CACert: ca.CertPEM(), Certificate: cert}
+
+	resources := infra.Resources("v1")
+	if len(resources) != 4 {
+		t.Fatalf("expected a deployment, service, configmap and secret, found %d resources", len(resources))
+	}
+
+	cm := resources[2].(*corev1.ConfigMap)
+	bootstrap := cm.Data[bootstrapFile]
+	for _, want := range []string{"id: default/gw-proxy", "address: xds.example.com", "port_value: 18000", "sni: xds.example.com"} {
+		if !strings.Contains(bootstrap, want) {
+			t.Errorf("expected the bootstrap to contain %q, found:\n%s", want, bootstrap)
+		}
+	}
+	// The proxies only accept an xDS server certificate issued to the xDS host.
+	var parsed map[string]any
+	if err := yaml.Unmarshal([]byte(bootstrap), &parsed); err != nil {
+		t.Fatalf("expected a YAML bootstrap, found %v", err)
+	}
+	validation := dig(parsed, "static_resources", "clusters", 0, "transport_socket", "typed_config",
+		"common_tls_context", "combined_validation_context")
+	san := dig(validation, "default_validation_context", "match_typed_subject_alt_names", 0)
+	if dig(san, "san_type") != "DNS" || dig(san, "matcher", "exact") != "xds.example.com" {
+		t.Errorf("expected the xDS server certificate to be matched against xds.example.com, found %v", validation)
+	}
+	if dig(validation, "validation_context_sds_secret_config", "name") != xdsCASecretName {
+		t.Errorf("expected the CA to be validated from SDS, found %v", validation)
+	}
+
+	// The admin API is only reachable from the pod, and the stats listener forwards the
+	// readiness and metrics requests to it.
+	if addr := dig(parsed, "admin", "address", "socket_address"); dig(addr, "address") != "127.0.0.1" {
+		t.Errorf("expected the admin API to be bound to the loopback interface, found %v", addr)
+	}
+	listener := dig(parsed, "static_resources", "listeners", 0)
+	if port := dig(listener, "address", "socket_address", "port_value"); port != float64(StatsPort) {
+		t.Errorf("expected the stats listener on port %d, found %v", StatsPort, port)
+	}
+	routes := dig(listener, "filter_chains", 0, "filters", 0, "typed_config", "route_config", "virtual_hosts", 0, "routes")
+	var paths []string
+	for _, r := range routes.([]any) {
+		if dig(r, "route", "cluster") != adminClusterName {
+			t.Errorf("expected the stats listener to route to the admin API, found %v", r)
+		}
+		paths = append(paths, fmt.Sprint(dig(r, "match", "path")))
+	}
+	if got := strings.Join(paths, ","); got != "/ready,/stats/prometheus" {
+		t.Errorf("expected the stats listener to only serve /ready and /stats/prometheus, found %s", got)
+	}
+	admin := dig(parsed, "static_resources", "clusters", 1)
+	addr := dig(admin, "load_assignment", "endpoints", 0, "lb_endpoints", 0, "endpoint", "address", "socket_address")
+	if dig(addr, "address") != "127.0.0.1" || dig(addr, "port_value") != float64(AdminPort) {
+		t.Errorf("expected the admin cluster to point at the admin API, found %v", admin)
+	}
+	if !strings.Contains(cm.Data[sdsCertFile], certsDir+"/tls.key") || !strings.Contains(cm.Data[sdsCAFile], certsDir+"/ca.crt") {
+		t.Errorf("expected the SDS resources to read the mounted certificates, found %v", cm.Data)
+	}
+
+	secret := resources[3].(*corev1.Secret)
+	if secret.Type != corev1.SecretTypeTLS || string(secret.Data[corev1.TLSCertKey]) != string(cert.CertPEM) ||
+		string(secret.Data["ca.crt"]) != string(ca.CertPEM()) {
+		t.Errorf("expected a TLS secret with the certificate and CA, found %+v", secret)
+	}
+
+	deploy := resources[0].(*appsv1.Deployment)
+	container := deploy.Spec.Template.Spec.Containers[0]
+	if probe := container.ReadinessProbe; probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Path != "/ready" ||
+		probe.HTTPGet.Port.String() != "stats" {
+		t.Errorf("expected a readiness probe on the stats listener, found %+v", probe)
+	}
+	if port := container.Ports[len(container.Ports)-1]; port.Name != "stats" || port.ContainerPort != StatsPort {
+		t.Errorf("expected the stats port on the proxy container, found %+v", container.Ports)
+	}
+	if len(container.VolumeMounts) != 2 || !strings.HasPrefix(strings.Join(container.Args, " "), "-c /etc/envoy/envoy.yaml") {
+		t.Errorf("expected the bootstrap to be mounted and loaded, found %+v", container)
+	}
+	hash := deploy.Spec.Template.Annotations[AnnotationBootstrapHash]
+	if hash == "" {
+		t.Fatal("expected the hash of the bootstrap on the pod template")
+	}
+
+	// A renewed certificate doesn't roll the proxies out, but a new xDS address does.
+	renewed, err := ca.Issue("default/gw-proxy", time.Hour, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	infra.XDS.Certificate = renewed
+	if got := infra.Deployment("v1").Spec.Template.Annotations[AnnotationBootstrapHash]; got != hash {
+		t.Errorf("expected the bootstrap hash to be unchanged by a renewal, found %s and %s", hash, got)
+	}
+	infra.XDS.Address = "xds.example.com:18001"
+	if got := infra.Deployment("v1").Spec.Template.Annotations[AnnotationBootstrapHash]; got == hash {
+		t.Error("expected the bootstrap hash to change with the xDS address")
+	}
+}
+
+// dig returns the value at path in v, made of maps indexed by strings and slices
+// indexed by ints, or nil if there's none.
+func dig(v any, path ...any) any {
+	for _, p := range path {
+		switch k := p.(type) {
+		case string:
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil
+			}
+			v = m[k]
+		case int:
+			l, ok := v.([]any)
+			if !ok || k >= len(l) {
+				return nil
+			}
+			v = l[k]
+		}
+	}
+	return v
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provisioner computes the infrastructure provisioned for each Gateway, a proxy
+// Deployment, the Service exposing it, the bootstrap ConfigMap and xDS certificate Secret
+// of the proxy, and optionally their HorizontalPodAutoscaler and PodDisruptionBudget, from
+// the GatewayClassConfig of its class and the overrides of the Gateway. The Gateways of a
+// class can instead be merged onto a shared proxy.
+package provisioner
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+// Annotations of a Gateway that override the configuration of its infrastructure.
+const (
+	// AnnotationParameters names the GatewayConfig in the namespace of the Gateway that
+	// overrides the GatewayClassConfig of its class.
+	AnnotationParameters = "gateway.sample.io/parameters"
+	// AnnotationReplicas overrides the number of proxy replicas.
+	AnnotationReplicas = "gateway.sample.io/replicas"
+	// AnnotationLoadBalancerClass overrides the load balancer class of the Service.
+	AnnotationLoadBalancerClass = "gateway.sample.io/load-balancer-class"
+)
+
+// Infra is the infrastructure of a Gateway.
+type Infra struct {
+	// Gateway is the Gateway the infrastructure is provisioned for.
+	Gateway *gwapiv1.Gateway
+	// Config is the merged proxy configuration of the Gateway, with defaults set.
+	Config cfgv1b1.GatewayClassConfigSpec
+	// Labels are added to the provisioned resources and proxy pods.
+	Labels map[string]string
+	// Annotations are added to the provisioned resources and proxy pods.
+	Annotations map[string]string
+	// IPs are the IP addresses requested by the Gateway.
+	IPs []string
+	// Hostnames are the hostname addresses requested by the Gateway. They are reported
+	// in the Gateway status but not assigned to the Service.
+	Hostnames []string
+	// Merged is the infrastructure shared by the Gateways the Gateway is merged with, or
+	// nil if the Gateway has its own proxy or couldn't be merged.
+	Merged *MergedInfra
+	// XDS is the connection of the proxy of the Gateway to the xDS server. The proxy
+	// isn't bootstrapped if it is nil.
+	XDS *XDS
+}
+
+// NewInfra returns the infrastructure of gw. The configuration is merged from, in
+// increasing order of precedence:
+//
+//  1. class, the GatewayClassConfig of the class of gw,
+//  2. params, the GatewayConfig named by the parameters annotation of gw,
+//  3. the spec.infrastructure labels and annotations of gw, whose annotations are
+//     also added to the Service annotations,
+//  4. the replicas and load balancer class annotations of gw.
+//
+// The overrides don't apply to a Gateway of a class that merges Gateways, which is
+// configured by class alone. class and params may be nil. The proxy image defaults to
+// defaultImage. An error is returned if an override is malformed or the merged
+// configuration is invalid, and an UnsupportedAddressError if the requested addresses
+// can't be assigned.
+func NewInfra(gw *gwapiv1.Gateway, class *cfgv1b1.GatewayClassConfigSpec, params *cfgv1b1.GatewayConfigSpec, defaultImage string) (*Infra, error) {
+	infra := &Infra{
+		Gateway:     gw,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	cfg := &infra.Config
+	if class != nil {
+		merge(cfg, class.Deployment, class.Service)
+		if class.MergeGateways != nil {
+			scope := *class.MergeGateways
+			cfg.MergeGateways = &scope
+		}
+	}
+	if cfg.MergeGateways == nil {
+		if err := infra.setOverrides(params); err != nil {
+			return nil, err
+		}
+	}
+
+	cfgv1b1.SetSpecDefaults(cfg)
+	if cfg.Deployment.Container == nil {
+		cfg.Deployment.Container = &cfgv1b1.ProxyContainer{}
+	}
+	if cfg.Deployment.Container.Image == "" {
+		cfg.Deployment.Container.Image = defaultImage
+	}
+
+	if errs := cfgv1b1.ValidateSpec(cfg, field.NewPath("spec")); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid infrastructure configuration: %w", errs.ToAggregate())
+	}
+
+	if err := infra.setAddresses(); err != nil {
+		return nil, err
+	}
+
+	return infra, nil
+}
+
+// setOverrides merges the overrides of the Gateway, params and those of the Gateway
+// itself, into the configuration.
+func (i *Infra) setOverrides(params *cfgv1b1.GatewayConfigSpec) error {
+	gw, cfg := i.Gateway, &i.Config
+	if params != nil {
+		merge(cfg, params.Deployment, params.Service)
+	}
+
+	if gwInfra := gw.Spec.Infrastructure; gwInfra != nil {
+		for k, v := range gwInfra.Labels {
+			i.Labels[string(k)] = string(v)
+		}
+		annotations := map[string]string{}
+		for k, v := range gwInfra.Annotations {
+			i.Annotations[string(k)] = string(v)
+			annotations[string(k)] = string(v)
+		}
+		merge(cfg, nil, &cfgv1b1.ProxyService{Annotations: annotations})
+	}
+
+	if v, ok := gw.Annotations[AnnotationReplicas]; ok {
+		replicas, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid %s annotation %q: must be an integer", AnnotationReplicas, v)
+		}
+		r := int32(replicas)
+		merge(cfg, &cfgv1b1.ProxyDeployment{Replicas: &r}, nil)
+	}
+	if v, ok := gw.Annotations[AnnotationLoadBalancerClass]; ok {
+		merge(cfg, nil, &cfgv1b1.ProxyService{LoadBalancerClass: &v})
+	}
+
+	return nil
+}
+
+// merge sets the fields of cfg that are set in deploy and svc. The Service annotations
+// are added to those of cfg.
+func merge(cfg *cfgv1b1.GatewayClassConfigSpec, deploy *cfgv1b1.ProxyDeployment, svc *cfgv1b1.ProxyService) {
+	if deploy != nil {
+		if cfg.Deployment == nil {
+			cfg.Deployment = &cfgv1b1.ProxyDeployment{}
+		}
+		if deploy.Replicas != nil {
+			replicas := *deploy.Replicas
+			cfg.Deployment.Replicas = &replicas
+		}
+		if c := deploy.Container; c != nil {
+			if cfg.Deployment.Container == nil {
+				cfg.Deployment.Container = &cfgv1b1.ProxyContainer{}
+			}
+			if c.Image != "" {
+				cfg.Deployment.Container.Image = c.Image
+			}
+			if c.Resources != nil {
+				cfg.Deployment.Container.Resources = c.Resources.DeepCopy()
+			}
+		}
+		if deploy.Autoscaling != nil {
+			cfg.Deployment.Autoscaling = deploy.Autoscaling.DeepCopy()
+		}
+		if deploy.PodDisruptionBudget != nil {
+			cfg.Deployment.PodDisruptionBudget = deploy.PodDisruptionBudget.DeepCopy()
+		}
+		if deploy.DrainTimeout != nil {
+			timeout := *deploy.DrainTimeout
+			cfg.Deployment.DrainTimeout = &timeout
+		}
+	}
+
+	if svc != nil {
+		if cfg.Service == nil {
+			cfg.Service = &cfgv1b1.ProxyService{}
+		}
+		if svc.Type != nil {
+			svcType := *svc.Type
+			cfg.Service.Type = &svcType
+		}
+		if len(svc.Annotations) > 0 && cfg.Service.Annotations == nil {
+			cfg.Service.Annotations = map[string]string{}
+		}
+		for k, v := range svc.Annotations {
+			cfg.Service.Annotations[k] = v
+		}
+		if svc.LoadBalancerClass != nil {
+			lbClass := *svc.LoadBalancerClass
+			cfg.Service.LoadBalancerClass = &lbClass
+		}
+		if svc.ExternalTrafficPolicy != nil {
+			policy := *svc.ExternalTrafficPolicy
+			cfg.Service.ExternalTrafficPolicy = &policy
+		}
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+const testImage = "docker.io/envoyproxy/envoy:v1.28.0"
+
+func newGateway(annotations map[string]string) *gwapiv1.Gateway {
+	return &gwapiv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gw", Annotations: annotations},
+		Spec: gwapiv1.GatewaySpec{
+			GatewayClassName: "test",
+			Listeners: []gwapiv1.Listener{
+				{Name: "http", Protocol: gwapiv1.HTTPProtocolType, Port: 80},
+				{Name: "https", Protocol: gwapiv1.HTTPSProtocolType, Port: 443},
+			},
+		},
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestNewInfraDefaults(t *testing.T) {
+	infra, err := NewInfra(newGateway(nil), nil, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := infra.Config
+	if *cfg.Deployment.Replicas != cfgv1b1.DefaultProxyReplicas {
+		t.Errorf("expected the default replicas, found %d", *cfg.Deployment.Replicas)
+	}
+	if cfg.Deployment.Container.Image != testImage {
+		t.Errorf("expected the default image, found %q", cfg.Deployment.Container.Image)
+	}
+	if *cfg.Service.Type != cfgv1b1.DefaultProxyServiceType {
+		t.Errorf("expected the default service type, found %s", *cfg.Service.Type)
+	}
+}
+
+func TestNewInfraPrecedence(t *testing.T) {
+	class := &cfgv1b1.GatewayClassConfigSpec{
+		Deployment: &cfgv1b1.ProxyDeployment{
+			Replicas: int32Ptr(1),
+			Container: &cfgv1b1.ProxyContainer{
+				Image: "envoy:class",
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+			},
+		},
+		Service: &cfgv1b1.ProxyService{
+			Annotations:       map[string]string{"class": "class", "shared": "class"},
+			LoadBalancerClass: stringPtr("class"),
+		},
+	}
+	params := &cfgv1b1.GatewayConfigSpec{
+		Deployment: &cfgv1b1.ProxyDeployment{Replicas: int32Ptr(2)},
+		Service: &cfgv1b1.ProxyService{
+			Annotations:       map[string]string{"params": "params", "shared": "params"},
+			LoadBalancerClass: stringPtr("params"),
+		},
+	}
+	gw := newGateway(map[string]string{
+		AnnotationReplicas:          "3",
+		AnnotationLoadBalancerClass: "gateway",
+	})
+	gw.Spec.Infrastructure = &gwapiv1.GatewayInfrastructure{
+		Labels:      map[gwapiv1.AnnotationKey]gwapiv1.AnnotationValue{"team": "edge"},
+		Annotations: map[gwapiv1.AnnotationKey]gwapiv1.AnnotationValue{"shared": "infrastructure"},
+	}
+
+	infra, err := NewInfra(gw, class, params, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := infra.Config
+	if *cfg.Deployment.Replicas != 3 {
+		t.Errorf("expected the replicas of the gateway annotation, found %d", *cfg.Deployment.Replicas)
+	}
+	if cfg.Deployment.Container.Image != "envoy:class" {
+		t.Errorf("expected the image of the class, found %q", cfg.Deployment.Container.Image)
+	}
+	if cfg.Deployment.Container.Resources == nil {
+		t.Errorf("expected the resources of the class")
+	}
+	if *cfg.Service.LoadBalancerClass != "gateway" {
+		t.Errorf("expected the load balancer class of the gateway annotation, found %q", *cfg.Service.LoadBalancerClass)
+	}
+	wantAnnotations := map[string]string{"class": "class", "params": "params", "shared": "infrastructure"}
+	for k, v := range wantAnnotations {
+		if cfg.Service.Annotations[k] != v {
+			t.Errorf("expected service annotation %s=%s, found %v", k, v, cfg.Service.Annotations)
+		}
+	}
+	if infra.Labels["team"] != "edge" || infra.Annotations["shared"] != "infrastructure" {
+		t.Errorf("expected the infrastructure labels and annotations, found %v and %v", infra.Labels, infra.Annotations)
+	}
+
+	// The configurations are merged into a copy.
+	if *class.Deployment.Replicas != 1 || class.Service.Annotations["shared"] != "class" {
+		t.Errorf("expected the class configuration to be unchanged, found %+v", class)
+	}
+}
+
+func TestNewInfraErrors(t *testing.T) {
+	clusterIP := corev1.ServiceTypeClusterIP
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		class       *cfgv1b1.GatewayClassConfigSpec
+		wantErr     string
+	}{
+		{
+			name:        "malformed replicas",
+			annotations: map[string]string{AnnotationReplicas: "many"},
+			wantErr:     AnnotationReplicas,
+		},
+		{
+			name:        "negative replicas",
+			annotations: map[string]string{AnnotationReplicas: "-1"},
+			wantErr:     "spec.deployment.replicas",
+		},
+		{
+			name:        "load balancer class of a ClusterIP service",
+			annotations: map[string]string{AnnotationLoadBalancerClass: "example.com/lb"},
+			class:       &cfgv1b1.GatewayClassConfigSpec{Service: &cfgv1b1.ProxyService{Type: &clusterIP}},
+			wantErr:     "spec.service.loadBalancerClass",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewInfra(newGateway(tc.annotations), tc.class, nil, testImage)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected an error for %s, found %v", tc.wantErr, err)
+			}
+		})
+	}
+}
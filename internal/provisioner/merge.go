@@ -0,0 +1,125 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+// MergedInfra is the infrastructure shared by the Gateways of a GatewayClass, or of a
+// GatewayClass in a namespace, merged onto a proxy Deployment and Service. The listener
+// ports of the merged Gateways don't collide, so each port of the proxy serves the
+// listeners of a single Gateway.
+type MergedInfra struct {
+	// Key is the name of the shared resources.
+	Key types.NamespacedName
+	// GatewayClass owns the shared resources.
+	GatewayClass *gwapiv1.GatewayClass
+	// Config is the proxy configuration of the GatewayClass, with defaults set.
+	Config cfgv1b1.GatewayClassConfigSpec
+	// Gateways are the merged Gateways, oldest first.
+	Gateways []*gwapiv1.Gateway
+	// XDS is the connection of the shared proxy to the xDS server. The proxy isn't
+	// bootstrapped if it is nil.
+	XDS *XDS
+}
+
+// ListenerConflictError is returned by Merge for a Gateway with a listener port that
+// collides with a listener of an older Gateway merged onto the same proxy.
+type ListenerConflictError struct {
+	Message string
+}
+
+func (e *ListenerConflictError) Error() string {
+	return e.Message
+}
+
+// MergedKey returns the name of the resources shared by the Gateways merged with gw,
+// whose GatewayClass is configured by the GatewayClassConfig in cfgNamespace to merge
+// its Gateways in scope.
+func MergedKey(gw *gwapiv1.Gateway, scope cfgv1b1.MergeGatewaysScope, cfgNamespace string) types.NamespacedName {
+	namespace := gw.Namespace
+	if scope == cfgv1b1.MergeGatewaysGatewayClass {
+		namespace = cfgNamespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: MergedResourceName(string(gw.Spec.GatewayClassName))}
+}
+
+// Merge merges the Gateways of infras, of the GatewayClass gc, onto the shared resources
+// named key, and sets the Merged infrastructure of each merged Gateway. The Gateways are
+// merged oldest first, and a Gateway with a listener port that collides with one of an
+// older Gateway isn't merged: a ListenerConflictError is returned for it instead. All
+// infras have the configuration of gc.
+func Merge(gc *gwapiv1.GatewayClass, key types.NamespacedName, infras []*Infra) (*MergedInfra, map[types.NamespacedName]error) {
+	sort.Slice(infras, func(a, b int) bool {
+		return isOlder(infras[a].Gateway, infras[b].Gateway)
+	})
+
+	merged := &MergedInfra{Key: key, GatewayClass: gc}
+	conflicts := map[types.NamespacedName]error{}
+	owners := map[corev1.ServicePort]*gwapiv1.Gateway{}
+	for _, infra := range infras {
+		gw := infra.Gateway
+		if err := listenerConflict(gw, owners); err != nil {
+			conflicts[types.NamespacedName{Namespace: gw.Namespace, Name: gw.Name}] = err
+			continue
+		}
+		for _, port := range ports([]*gwapiv1.Gateway{gw}) {
+			owners[port] = gw
+		}
+		if len(merged.Gateways) == 0 {
+			merged.Config = infra.Config
+		}
+		merged.Gateways = append(merged.Gateways, gw)
+		infra.Merged = merged
+	}
+
+	return merged, conflicts
+}
+
+// listenerConflict returns a ListenerConflictError if a listener port of gw is one of
+// owners, the ports of the Gateways merged before it.
+func listenerConflict(gw *gwapiv1.Gateway, owners map[corev1.ServicePort]*gwapiv1.Gateway) error {
+	for _, port := range ports([]*gwapiv1.Gateway{gw}) {
+		if owner, ok := owners[port]; ok {
+			return &ListenerConflictError{
+				Message: fmt.Sprintf("listener port %d/%s collides with gateway %s/%s merged onto the same proxy",
+					port.Port, port.Protocol, owner.Namespace, owner.Name),
+			}
+		}
+	}
+	return nil
+}
+
+// isOlder returns true if a was created before b, ordering Gateways created at the same
+// time by namespace and name.
+func isOlder(a, b *gwapiv1.Gateway) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
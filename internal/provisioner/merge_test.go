@@ -0,0 +1,109 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+func newMergedInfra(t *testing.T, name string, created time.Time, ports ...gwapiv1.PortNumber) *Infra {
+	t.Helper()
+	gw := newGateway(map[string]string{AnnotationReplicas: "3"})
+	gw.Name = name
+	gw.CreationTimestamp = metav1.NewTime(created)
+	gw.Spec.Listeners = nil
+	for _, port := range ports {
+		gw.Spec.Listeners = append(gw.Spec.Listeners, gwapiv1.Listener{Protocol: gwapiv1.HTTPProtocolType, Port: port})
+	}
+	scope := cfgv1b1.MergeGatewaysNamespace
+	infra, err := NewInfra(gw, &cfgv1b1.GatewayClassConfigSpec{MergeGateways: &scope}, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return infra
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Now()
+	older := newMergedInfra(t, "older", now, 80, 443)
+	newer := newMergedInfra(t, "newer", now.Add(time.Second), 8080)
+	conflicting := newMergedInfra(t, "conflicting", now.Add(2*time.Second), 8443, 443)
+
+	gc := &gwapiv1.GatewayClass{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: "uid"}}
+	key := MergedKey(older.Gateway, cfgv1b1.MergeGatewaysNamespace, "config")
+	merged, conflicts := Merge(gc, key, []*Infra{conflicting, newer, older})
+
+	if got := len(merged.Gateways); got != 2 || merged.Gateways[0].Name != "older" {
+		t.Fatalf("expected the older and newer gateways to be merged oldest first, found %d", got)
+	}
+	if older.Merged != merged || newer.Merged != merged || conflicting.Merged != nil {
+		t.Errorf("expected only the merged gateways to share the merged infrastructure")
+	}
+	var conflictErr *ListenerConflictError
+	err := conflicts[types.NamespacedName{Namespace: "default", Name: "conflicting"}]
+	if !errors.As(err, &conflictErr) || !strings.Contains(err.Error(), "443/TCP") {
+		t.Errorf("expected a conflict on port 443, found %v", err)
+	}
+	if *merged.Config.Deployment.Replicas != cfgv1b1.DefaultProxyReplicas {
+		t.Errorf("expected the replicas override of the gateway to be ignored, found %d", *merged.Config.Deployment.Replicas)
+	}
+
+	if got, want := older.ProxyKey(), (types.NamespacedName{Namespace: "default", Name: MergedResourceName("test")}); got != want {
+		t.Errorf("expected the shared resources %s, found %s", want, got)
+	}
+	svc := merged.Resources("v1")[1]
+	if ref := svc.GetOwnerReferences()[0]; ref.Kind != "GatewayClass" || ref.Name != gc.Name {
+		t.Errorf("expected the gatewayclass to own the shared service, found %+v", ref)
+	}
+	var ports []string
+	for _, p := range merged.proxy("v1").ports {
+		ports = append(ports, p.Name)
+	}
+	if got, want := strings.Join(ports, ","), "tcp-80,tcp-443,tcp-8080"; got != want {
+		t.Errorf("expected the ports of the merged gateways %s, found %s", want, got)
+	}
+}
+
+func TestMergedKey(t *testing.T) {
+	gw := newGateway(nil)
+	if key := MergedKey(gw, cfgv1b1.MergeGatewaysGatewayClass, "config"); key.Namespace != "config" {
+		t.Errorf("expected the namespace of the gatewayclassconfig, found %s", key.Namespace)
+	}
+	if key := MergedKey(gw, cfgv1b1.MergeGatewaysNamespace, "config"); key.Namespace != gw.Namespace {
+		t.Errorf("expected the namespace of the gateway, found %s", key.Namespace)
+	}
+}
+
+func TestMergedAddresses(t *testing.T) {
+	gw := newGateway(nil)
+	gw.Spec.Addresses = []gwapiv1.GatewayAddress{{Value: "10.0.0.1"}}
+	scope := cfgv1b1.MergeGatewaysGatewayClass
+	_, err := NewInfra(gw, &cfgv1b1.GatewayClassConfigSpec{MergeGateways: &scope}, nil, testImage)
+	var addrErr *UnsupportedAddressError
+	if !errors.As(err, &addrErr) {
+		t.Errorf("expected an unsupported address error, found %v", err)
+	}
+}
@@ -0,0 +1,446 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+// Labels of the provisioned resources and proxy pods.
+const (
+	// LabelGatewayName is the name of the Gateway of the proxy.
+	LabelGatewayName = "gateway.sample.io/name"
+	// LabelGatewayClassName is the name of the GatewayClass of a proxy shared by merged
+	// Gateways.
+	LabelGatewayClassName = "gateway.sample.io/gatewayclass"
+	labelAppName          = "app.kubernetes.io/name"
+	labelManagedBy        = "app.kubernetes.io/managed-by"
+	appName               = "sample-gateway-proxy"
+	managedBy             = "sample-gateway-manager"
+)
+
+// proxyContainerName is the name of the proxy container of the Deployment.
+const proxyContainerName = "proxy"
+
+// AdminPort is the port of the Envoy admin API of the proxies, bound to the loopback
+// interface. StatsPort is the port of the listener of the proxies serving their
+// readiness on /ready and their Prometheus metrics on /stats/prometheus from the admin
+// API, the only admin endpoints reachable from outside the pod.
+const (
+	AdminPort = 19000
+	StatsPort = 19001
+)
+
+// drainPath is the admin API endpoint starting the graceful drain of the listeners of
+// Envoy, which lasts for its --drain-time-s. It must be requested with a POST.
+const drainPath = "/drain_listeners?graceful"
+
+// terminationMargin is the time a proxy is given to exit after draining.
+const terminationMargin = 10 * time.Second
+
+// maxNameLength is the maximum length of the name of a Service.
+const maxNameLength = 63
+
+// ResourceName returns the name of the resources provisioned for the named Gateway. Long
+// names are truncated and suffixed with a hash of the Gateway name to keep them unique.
+func ResourceName(gwName string) string {
+	name := gwName + "-proxy"
+	if len(name) <= maxNameLength {
+		return name
+	}
+	return hashedName(name, gwName)
+}
+
+// MergedResourceName returns the name of the resources shared by the merged Gateways of
+// the named GatewayClass. It always ends with a hash of the GatewayClass kind and name,
+// so it can't be the name of the resources of a Gateway: those end with "-proxy", which
+// isn't hexadecimal, or with a hash of a Gateway name, which can't contain a "/".
+func MergedResourceName(className string) string {
+	return hashedName(className+"-merged", "GatewayClass/"+className)
+}
+
+// hashedName returns name suffixed with a hash of key, truncating name to keep the
+// result a valid Service name.
+func hashedName(name, key string) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(key)))[:8]
+	if len(name)+len(hash)+1 > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength-len(hash)-1], "-.")
+	}
+	return name + "-" + hash
+}
+
+// OptionalResources returns the resources of the proxy named key that are only
+// provisioned if configured, with only their type and name set.
+func OptionalResources(key types.NamespacedName) []client.Object {
+	meta := metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}
+	return []client.Object{
+		&autoscalingv2.HorizontalPodAutoscaler{
+			TypeMeta:   metav1.TypeMeta{APIVersion: autoscalingv2.SchemeGroupVersion.String(), Kind: "HorizontalPodAutoscaler"},
+			ObjectMeta: meta,
+		},
+		&policyv1.PodDisruptionBudget{
+			TypeMeta:   metav1.TypeMeta{APIVersion: policyv1.SchemeGroupVersion.String(), Kind: "PodDisruptionBudget"},
+			ObjectMeta: meta,
+		},
+	}
+}
+
+// IsProvisioned returns true if obj is a resource provisioned by the manager.
+func IsProvisioned(obj metav1.Object) bool {
+	return obj.GetLabels()[labelManagedBy] == managedBy
+}
+
+// ProxyKey returns the name of the resources of the Gateway: those shared with the
+// Gateways it is merged with, or its own.
+func (i *Infra) ProxyKey() types.NamespacedName {
+	if i.Merged != nil {
+		return i.Merged.Key
+	}
+	return types.NamespacedName{Namespace: i.Gateway.Namespace, Name: ResourceName(i.Gateway.Name)}
+}
+
+// Resources returns the resources provisioned for the Gateway, owned by the Gateway of
+// the API version gwVersion.
+func (i *Infra) Resources(gwVersion string) []client.Object {
+	return i.proxy(gwVersion).resources()
+}
+
+// Deployment returns the proxy Deployment of the Gateway.
+func (i *Infra) Deployment(gwVersion string) *appsv1.Deployment {
+	return i.proxy(gwVersion).deployment()
+}
+
+// Service returns the Service exposing the proxies of the Gateway.
+func (i *Infra) Service(gwVersion string) *corev1.Service {
+	return i.proxy(gwVersion).service()
+}
+
+func (i *Infra) proxy(gwVersion string) *proxy {
+	gw := i.Gateway
+	return &proxy{
+		key: i.ProxyKey(),
+		owner: metav1.OwnerReference{
+			APIVersion:         gwapiv1.GroupName + "/" + gwVersion,
+			Kind:               "Gateway",
+			Name:               gw.Name,
+			UID:                gw.UID,
+			Controller:         ptr.To(true),
+			BlockOwnerDeletion: ptr.To(true),
+		},
+		config:      &i.Config,
+		selector:    map[string]string{labelAppName: appName, LabelGatewayName: gw.Name},
+		labels:      i.Labels,
+		annotations: i.Annotations,
+		ports:       ports([]*gwapiv1.Gateway{gw}),
+		ips:         i.IPs,
+		xds:         i.XDS,
+	}
+}
+
+// Resources returns the resources shared by the merged Gateways, owned by the
+// GatewayClass of the API version gcVersion.
+func (m *MergedInfra) Resources(gcVersion string) []client.Object {
+	return m.proxy(gcVersion).resources()
+}
+
+func (m *MergedInfra) proxy(gcVersion string) *proxy {
+	return &proxy{
+		key: m.Key,
+		owner: metav1.OwnerReference{
+			APIVersion:         gwapiv1.GroupName + "/" + gcVersion,
+			Kind:               "GatewayClass",
+			Name:               m.GatewayClass.Name,
+			UID:                m.GatewayClass.UID,
+			Controller:         ptr.To(true),
+			BlockOwnerDeletion: ptr.To(true),
+		},
+		config:   &m.Config,
+		selector: map[string]string{labelAppName: appName, LabelGatewayClassName: m.GatewayClass.Name},
+		ports:    ports(m.Gateways),
+		xds:      m.XDS,
+	}
+}
+
+// proxy is a proxy Deployment and the Service exposing it.
+type proxy struct {
+	key   types.NamespacedName
+	owner metav1.OwnerReference
+	// config is the configuration of the proxy, with defaults set.
+	config *cfgv1b1.GatewayClassConfigSpec
+	// selector are the labels selecting the proxy pods.
+	selector map[string]string
+	// labels and annotations are added to the resources and proxy pods.
+	labels      map[string]string
+	annotations map[string]string
+	ports       []corev1.ServicePort
+	ips         []string
+	// xds is the connection of the proxy to the xDS server, or nil if the proxy isn't
+	// bootstrapped.
+	xds *XDS
+}
+
+// resources returns the Deployment and Service of the proxy, followed by its bootstrap
+// ConfigMap and xDS certificate Secret, and its configured HorizontalPodAutoscaler and
+// PodDisruptionBudget.
+func (p *proxy) resources() []client.Object {
+	res := []client.Object{p.deployment(), p.service()}
+	if p.xds != nil {
+		res = append(res, p.configMap(), p.secret())
+	}
+	if hpa := p.horizontalPodAutoscaler(); hpa != nil {
+		res = append(res, hpa)
+	}
+	if pdb := p.podDisruptionBudget(); pdb != nil {
+		res = append(res, pdb)
+	}
+	return res
+}
+
+func (p *proxy) deployment() *appsv1.Deployment {
+	deploy := p.config.Deployment
+	container := corev1.Container{
+		Name:  proxyContainerName,
+		Image: deploy.Container.Image,
+	}
+	for _, port := range p.ports {
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.Port,
+			Protocol:      port.Protocol,
+		})
+	}
+	if deploy.Container.Resources != nil {
+		container.Resources = *deploy.Container.Resources
+	}
+
+	// The replicas of an autoscaled Deployment are owned by its autoscaler.
+	replicas := deploy.Replicas
+	if deploy.Autoscaling != nil {
+		replicas = nil
+	}
+
+	// The proxies drain their listeners before they are terminated, and a rollout only
+	// terminates proxies once their replacement is available.
+	drain := deploy.DrainTimeout.Duration
+	gracePeriod := int64((drain + terminationMargin) / time.Second)
+	container.Args = []string{"-c", bootstrapDir + "/" + bootstrapFile}
+	if drain > 0 {
+		container.Args = append(container.Args, "--drain-time-s", strconv.Itoa(seconds(drain)))
+		container.Lifecycle = &corev1.Lifecycle{
+			PreStop: &corev1.LifecycleHandler{
+				Exec: &corev1.ExecAction{Command: drainCommand(drain)},
+			},
+		}
+	}
+	maxUnavailable := intstr.FromInt32(0)
+
+	podAnnotations := p.annotations
+	podSpec := corev1.PodSpec{
+		Containers:                    []corev1.Container{container},
+		TerminationGracePeriodSeconds: &gracePeriod,
+	}
+	if p.xds != nil {
+		podAnnotations = map[string]string{AnnotationBootstrapHash: p.bootstrapHash()}
+		for k, v := range p.annotations {
+			podAnnotations[k] = v
+		}
+		p.mountBootstrap(&podSpec)
+	}
+
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+		ObjectMeta: p.objectMeta(p.annotations),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: p.selector},
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      p.allLabels(),
+					Annotations: podAnnotations,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+// drainCommand returns the preStop command of the proxy container, which starts the
+// drain with a POST to the admin API and waits for the drain timeout, since Envoy exits
+// as soon as it receives SIGTERM. The Envoy image has no HTTP client, so the request is
+// written with the /dev/tcp redirection of bash.
+func drainCommand(timeout time.Duration) []string {
+	request := fmt.Sprintf(`POST %s HTTP/1.1\r\nHost: 127.0.0.1\r\nContent-Length: 0\r\nConnection: close\r\n\r\n`, drainPath)
+	return []string{"/bin/bash", "-c", fmt.Sprintf(
+		"exec 3<>/dev/tcp/127.0.0.1/%d && printf '%s' >&3 && cat <&3 >/dev/null; sleep %d",
+		AdminPort, request, seconds(timeout))}
+}
+
+// seconds returns d in whole seconds, rounded up.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func (p *proxy) service() *corev1.Service {
+	svcCfg := p.config.Service
+	svc := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Service"},
+		ObjectMeta: p.objectMeta(svcCfg.Annotations),
+		Spec: corev1.ServiceSpec{
+			Type:     *svcCfg.Type,
+			Selector: p.selector,
+			Ports:    p.ports,
+		},
+	}
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		svc.Spec.LoadBalancerClass = svcCfg.LoadBalancerClass
+		if len(p.ips) == 1 {
+			svc.Spec.LoadBalancerIP = p.ips[0]
+		}
+	} else {
+		svc.Spec.ExternalIPs = p.ips
+	}
+	if svc.Spec.Type != corev1.ServiceTypeClusterIP && svcCfg.ExternalTrafficPolicy != nil {
+		svc.Spec.ExternalTrafficPolicy = *svcCfg.ExternalTrafficPolicy
+	}
+
+	return svc
+}
+
+// horizontalPodAutoscaler returns the autoscaler of the proxy Deployment, or nil if
+// autoscaling isn't configured.
+func (p *proxy) horizontalPodAutoscaler() *autoscalingv2.HorizontalPodAutoscaler {
+	as := p.config.Deployment.Autoscaling
+	if as == nil {
+		return nil
+	}
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta:   metav1.TypeMeta{APIVersion: autoscalingv2.SchemeGroupVersion.String(), Kind: "HorizontalPodAutoscaler"},
+		ObjectMeta: p.objectMeta(nil),
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+				Name:       p.key.Name,
+			},
+			MinReplicas: as.MinReplicas,
+			MaxReplicas: as.MaxReplicas,
+			Metrics:     as.Metrics,
+		},
+	}
+}
+
+// podDisruptionBudget returns the disruption budget of the proxy pods, or nil if it
+// isn't configured.
+func (p *proxy) podDisruptionBudget() *policyv1.PodDisruptionBudget {
+	pdb := p.config.Deployment.PodDisruptionBudget
+	if pdb == nil {
+		return nil
+	}
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{APIVersion: policyv1.SchemeGroupVersion.String(), Kind: "PodDisruptionBudget"},
+		ObjectMeta: p.objectMeta(nil),
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: p.selector},
+			MinAvailable:   pdb.MinAvailable,
+			MaxUnavailable: pdb.MaxUnavailable,
+		},
+	}
+}
+
+// objectMeta returns the metadata of a provisioned resource with the annotations.
+func (p *proxy) objectMeta(annotations map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:       p.key.Namespace,
+		Name:            p.key.Name,
+		Labels:          p.allLabels(),
+		Annotations:     annotations,
+		OwnerReferences: []metav1.OwnerReference{p.owner},
+	}
+}
+
+// allLabels returns the labels of the provisioned resources: the labels of the proxy
+// and the selector labels, which take precedence.
+func (p *proxy) allLabels() map[string]string {
+	labels := map[string]string{labelManagedBy: managedBy}
+	for k, v := range p.labels {
+		labels[k] = v
+	}
+	for k, v := range p.selector {
+		labels[k] = v
+	}
+	return labels
+}
+
+// ports returns the Service ports of the listeners of gateways, one per port and
+// protocol, sorted by port.
+func ports(gateways []*gwapiv1.Gateway) []corev1.ServicePort {
+	seen := map[corev1.ServicePort]bool{}
+	var ports []corev1.ServicePort
+	for _, gw := range gateways {
+		for _, l := range gw.Spec.Listeners {
+			port := servicePort(l)
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	sort.Slice(ports, func(a, b int) bool {
+		if ports[a].Port == ports[b].Port {
+			return ports[a].Protocol < ports[b].Protocol
+		}
+		return ports[a].Port < ports[b].Port
+	})
+	return ports
+}
+
+// servicePort returns the Service port of the listener l.
+func servicePort(l gwapiv1.Listener) corev1.ServicePort {
+	protocol := corev1.ProtocolTCP
+	if l.Protocol == gwapiv1.UDPProtocolType {
+		protocol = corev1.ProtocolUDP
+	}
+	return corev1.ServicePort{
+		Name:       fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), l.Port),
+		Protocol:   protocol,
+		Port:       int32(l.Port),
+		TargetPort: intstr.FromInt32(int32(l.Port)),
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+)
+
+func TestResourceName(t *testing.T) {
+	if name := ResourceName("gw"); name != "gw-proxy" {
+		t.Errorf("expected gw-proxy, found %s", name)
+	}
+
+	long := strings.Repeat("a", 70)
+	name := ResourceName(long)
+	if len(name) > maxNameLength {
+		t.Errorf("expected at most %d characters, found %d", maxNameLength, len(name))
+	}
+	if other := ResourceName(long + "b"); other == name {
+		t.Errorf("expected distinct names for distinct long gateway names, found %s", name)
+	}
+}
+
+func TestMergedResourceName(t *testing.T) {
+	name := MergedResourceName("foo")
+	if !strings.HasPrefix(name, "foo-merged-") || len(name) != len("foo-merged-")+8 {
+		t.Errorf("expected foo-merged-<hash>, found %s", name)
+	}
+	if long := MergedResourceName(strings.Repeat("a", 70)); len(long) > maxNameLength {
+		t.Errorf("expected at most %d characters, found %d", maxNameLength, len(long))
+	}
+
+	// The merged resources of a class never share a name with the resources of a
+	// Gateway, including a Gateway named after the merged resources.
+	testCases := []struct {
+		class   string
+		gateway string
+	}{
+		{class: "foo", gateway: "foo-merged"},
+		{class: "foo", gateway: strings.TrimSuffix(name, "-proxy")},
+		{class: "foo", gateway: name},
+		{class: strings.Repeat("a", 70), gateway: strings.Repeat("a", 70)},
+		{class: strings.Repeat("a", 70), gateway: strings.Repeat("a", 60) + "-merged"},
+	}
+	for _, tc := range testCases {
+		if merged, gw := MergedResourceName(tc.class), ResourceName(tc.gateway); merged == gw {
+			t.Errorf("expected distinct names for class %s and gateway %s, found %s", tc.class, tc.gateway, merged)
+		}
+	}
+}
+
+func TestResources(t *testing.T) {
+	gw := newGateway(nil)
+	gw.Spec.Listeners = append(gw.Spec.Listeners,
+		gwapiv1.Listener{Name: "other-http", Protocol: gwapiv1.HTTPProtocolType, Port: 80},
+		gwapiv1.Listener{Name: "dns", Protocol: gwapiv1.UDPProtocolType, Port: 53},
+	)
+	gw.Spec.Infrastructure = &gwapiv1.GatewayInfrastructure{
+		Labels: map[gwapiv1.AnnotationKey]gwapiv1.AnnotationValue{LabelGatewayName: "overridden", "team": "edge"},
+	}
+	infra, err := NewInfra(gw, nil, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc := infra.Service("v1")
+	var ports []string
+	for _, p := range svc.Spec.Ports {
+		ports = append(ports, p.Name)
+	}
+	if got, want := strings.Join(ports, ","), "udp-53,tcp-80,tcp-443"; got != want {
+		t.Errorf("expected ports %s, found %s", want, got)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		t.Errorf("expected a LoadBalancer service, found %s", svc.Spec.Type)
+	}
+	if svc.Labels[LabelGatewayName] != gw.Name || svc.Labels["team"] != "edge" {
+		t.Errorf("expected the selector and infrastructure labels, found %v", svc.Labels)
+	}
+	if ref := svc.OwnerReferences[0]; ref.APIVersion != "gateway.networking.k8s.io/v1" || ref.Name != gw.Name {
+		t.Errorf("expected the gateway to own the service, found %+v", ref)
+	}
+
+	deploy := infra.Deployment("v1beta1")
+	if *deploy.Spec.Replicas != 1 {
+		t.Errorf("expected 1 replica, found %d", *deploy.Spec.Replicas)
+	}
+	if deploy.Spec.Template.Spec.Containers[0].Image != testImage {
+		t.Errorf("expected the default image, found %s", deploy.Spec.Template.Spec.Containers[0].Image)
+	}
+	if ref := deploy.OwnerReferences[0]; ref.APIVersion != "gateway.networking.k8s.io/v1beta1" {
+		t.Errorf("expected the owner reference of the gateway version, found %s", ref.APIVersion)
+	}
+}
+
+func TestAutoscaledResources(t *testing.T) {
+	minAvailable := intstr.FromInt32(1)
+	class := &cfgv1b1.GatewayClassConfigSpec{Deployment: &cfgv1b1.ProxyDeployment{
+		Replicas:            int32Ptr(2),
+		Autoscaling:         &cfgv1b1.ProxyAutoscaling{MaxReplicas: 5},
+		PodDisruptionBudget: &cfgv1b1.ProxyPodDisruptionBudget{MinAvailable: &minAvailable},
+	}}
+	infra, err := NewInfra(newGateway(nil), class, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resources := infra.Resources("v1")
+	if len(resources) != 4 {
+		t.Fatalf("expected a deployment, service, autoscaler and disruption budget, found %d resources", len(resources))
+	}
+	if deploy := resources[0].(*appsv1.Deployment); deploy.Spec.Replicas != nil {
+		t.Errorf("expected the replicas of the autoscaled deployment to be unset, found %d", *deploy.Spec.Replicas)
+	}
+	hpa := resources[2].(*autoscalingv2.HorizontalPodAutoscaler)
+	if hpa.Spec.ScaleTargetRef.Name != ResourceName("gw") || hpa.Spec.MaxReplicas != 5 || *hpa.Spec.MinReplicas != 1 {
+		t.Errorf("expected an autoscaler of the deployment with the defaulted min replicas, found %+v", hpa.Spec)
+	}
+	if len(hpa.Spec.Metrics) != 1 {
+		t.Errorf("expected the default CPU utilization metric, found %+v", hpa.Spec.Metrics)
+	}
+	pdb := resources[3].(*policyv1.PodDisruptionBudget)
+	if pdb.Spec.MinAvailable.IntValue() != 1 || pdb.Spec.Selector.MatchLabels[LabelGatewayName] != "gw" {
+		t.Errorf("expected a disruption budget of the proxy pods, found %+v", pdb.Spec)
+	}
+	if ref := pdb.OwnerReferences[0]; ref.Kind != "Gateway" || ref.Name != "gw" {
+		t.Errorf("expected the gateway to own the disruption budget, found %+v", ref)
+	}
+}
+
+func TestDrainingResources(t *testing.T) {
+	class := &cfgv1b1.GatewayClassConfigSpec{Deployment: &cfgv1b1.ProxyDeployment{
+		DrainTimeout: &metav1.Duration{Duration: 45 * time.Second},
+	}}
+	infra, err := NewInfra(newGateway(nil), class, nil, testImage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deploy := infra.Deployment("v1")
+	hook := deploy.Spec.Template.Spec.Containers[0].Lifecycle
+	if hook == nil || hook.PreStop == nil || hook.PreStop.Exec == nil {
+		t.Fatalf("expected a preStop hook draining the proxy, found %+v", hook)
+	}
+	if cmd := strings.Join(hook.PreStop.Exec.Command, " "); !strings.HasSuffix(cmd, "sleep 45") {
+		t.Errorf("expected the preStop hook to wait for the drain timeout, found %s", cmd)
+	}
+	if args := strings.Join(deploy.Spec.Template.Spec.Containers[0].Args, " "); !strings.HasSuffix(args, "--drain-time-s 45") {
+		t.Errorf("expected the proxy to drain for the drain timeout, found args %s", args)
+	}
+	if grace := deploy.Spec.Template.Spec.TerminationGracePeriodSeconds; grace == nil || *grace != 55 {
+		t.Errorf("expected the grace period to cover the drain timeout, found %v", grace)
+	}
+	if rolling := deploy.Spec.Strategy.RollingUpdate; rolling == nil || rolling.MaxUnavailable.IntValue() != 0 {
+		t.Errorf("expected a rollout keeping every proxy available, found %+v", deploy.Spec.Strategy)
+	}
+
+	class.Deployment.DrainTimeout.Duration = 0
+	if infra, err = NewInfra(newGateway(nil), class, nil, testImage); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hook := infra.Deployment("v1").Spec.Template.Spec.Containers[0].Lifecycle; hook != nil {
+		t.Errorf("expected no preStop hook without draining, found %+v", hook)
+	}
+}
+
+func TestDrainCommand(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+	// The command requests the admin API of the proxy, served here in its place.
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", AdminPort))
+	if err != nil {
+		t.Skipf("the admin port is not available: %v", err)
+	}
+	requests := make(chan *http.Request, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests <- req
+		fmt.Fprintln(rw, "OK")
+	})}
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	defer srv.Close()
+
+	cmd := drainCommand(time.Second)
+	if cmd[0] != "/bin/bash" {
+		t.Errorf("expected a bash command, found %s", cmd[0])
+	}
+	start := time.Now()
+	if out, err := exec.Command(bash, cmd[1:]...).CombinedOutput(); err != nil {
+		t.Fatalf("unexpected error: %v: %s", err, out)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the command to wait for the drain timeout, returned after %s", elapsed)
+	}
+
+	// Envoy only drains the listeners on a POST to /drain_listeners, gracefully with the
+	// graceful query parameter.
+	select {
+	case req := <-requests:
+		if req.Method != http.MethodPost || req.URL.Path != "/drain_listeners" || !req.URL.Query().Has("graceful") {
+			t.Errorf("expected POST /drain_listeners?graceful, found %s %s", req.Method, req.URL)
+		}
+	default:
+		t.Error("expected the command to request the admin API")
+	}
+}
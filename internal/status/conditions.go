@@ -17,45 +17,11 @@ limitations under the License.
 package status
 
 import (
-	"time"
-
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
-)
-
-const (
-	ReasonOlderGatewayClassExists gwapiv1b1.GatewayClassConditionReason = "OlderGatewayClassExists"
-
-	MsgOlderGatewayClassExists   = "An older GatewayClass with the same Spec.Controller exists"
-	MsgValidGatewayClass         = "Valid GatewayClass"
-	MsgGatewayClassInvalidParams = "Invalid parametersRef"
 )
 
-// computeGatewayClassAcceptedCondition computes the GatewayClass Accepted status condition.
-func computeGatewayClassAcceptedCondition(gc *gwapiv1b1.GatewayClass, cond *GatewayClassCondition) *metav1.Condition {
-	if gc == nil || cond == nil {
-		return nil
-	}
-	ret := &metav1.Condition{
-		Type:               string(gwapiv1b1.GatewayClassConditionStatusAccepted),
-		Reason:             cond.reason,
-		Message:            cond.message,
-		ObservedGeneration: gc.Generation,
-		LastTransitionTime: metav1.NewTime(time.Now()),
-	}
-
-	switch cond.accepted {
-	case true:
-		ret.Status = metav1.ConditionTrue
-	default:
-		ret.Status = metav1.ConditionFalse
-	}
-
-	return ret
-}
-
 // MergeConditions adds or updates matching conditions, and updates the transition
-// time if details of a condition have changed. Returns the updated condition array.
+// time if the status of a condition has changed. Returns the updated condition array.
 func MergeConditions(conditions []metav1.Condition, updates ...metav1.Condition) []metav1.Condition {
 	var additions []metav1.Condition
 	for i, update := range updates {
@@ -64,13 +30,15 @@ func MergeConditions(conditions []metav1.Condition, updates ...metav1.Condition)
 			if cond.Type == update.Type {
 				add = false
 				if conditionChanged(cond, update) {
+					if cond.Status != update.Status {
+						conditions[j].LastTransitionTime = update.LastTransitionTime
+					}
 					conditions[j].Status = update.Status
 					conditions[j].Reason = update.Reason
 					conditions[j].Message = update.Message
 					conditions[j].ObservedGeneration = update.ObservedGeneration
-					conditions[j].LastTransitionTime = update.LastTransitionTime
-					break
 				}
+				break
 			}
 		}
 		if add {
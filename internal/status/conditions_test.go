@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeConditions(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+	accepted := metav1.Condition{
+		Type: "Accepted", Status: metav1.ConditionTrue, Reason: "Accepted",
+		Message: "accepted", ObservedGeneration: 1, LastTransitionTime: before,
+	}
+
+	testCases := map[string]struct {
+		update   metav1.Condition
+		wantLen  int
+		wantTime metav1.Time
+	}{
+		"unchanged condition keeps transition time": {
+			update:   metav1.Condition{Type: "Accepted", Status: metav1.ConditionTrue, Reason: "Accepted", Message: "accepted", ObservedGeneration: 1, LastTransitionTime: now},
+			wantLen:  1,
+			wantTime: before,
+		},
+		"new generation keeps transition time": {
+			update:   metav1.Condition{Type: "Accepted", Status: metav1.ConditionTrue, Reason: "Accepted", Message: "accepted", ObservedGeneration: 2, LastTransitionTime: now},
+			wantLen:  1,
+			wantTime: before,
+		},
+		"status change updates transition time": {
+			update:   metav1.Condition{Type: "Accepted", Status: metav1.ConditionFalse, Reason: "Invalid", Message: "invalid", ObservedGeneration: 2, LastTransitionTime: now},
+			wantLen:  1,
+			wantTime: now,
+		},
+		"new condition is added": {
+			update:   metav1.Condition{Type: "Programmed", Status: metav1.ConditionTrue, Reason: "Programmed", LastTransitionTime: now},
+			wantLen:  2,
+			wantTime: now,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conds := MergeConditions([]metav1.Condition{accepted}, tc.update)
+			if len(conds) != tc.wantLen {
+				t.Fatalf("expected %d conditions, found %d", tc.wantLen, len(conds))
+			}
+			var got *metav1.Condition
+			for i := range conds {
+				if conds[i].Type == tc.update.Type {
+					got = &conds[i]
+				}
+			}
+			if got.Status != tc.update.Status || got.Reason != tc.update.Reason ||
+				got.Message != tc.update.Message || got.ObservedGeneration != tc.update.ObservedGeneration {
+				t.Errorf("expected condition %+v, found %+v", tc.update, *got)
+			}
+			if !got.LastTransitionTime.Equal(&tc.wantTime) {
+				t.Errorf("expected transition time %v, found %v", tc.wantTime, got.LastTransitionTime)
+			}
+		})
+	}
+}
@@ -20,10 +20,12 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
-	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
-	cfgv1a1 "solo.io/sample-gateway-manager/api/v1alpha1"
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/gatewayapi"
 )
 
 // IsEqual checks if two objects have equivalent status.
@@ -35,22 +37,23 @@ import (
 //		Gateway
 //		TCPRoute
 func IsEqual(objA, objB interface{}) bool {
+	objA, objB = toV1(objA), toV1(objB)
 	opts := cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")
 	switch a := objA.(type) {
-	case *cfgv1a1.GatewayClassConfig:
-		if b, ok := objB.(*cfgv1a1.GatewayClassConfig); ok {
+	case *cfgv1b1.GatewayClassConfig:
+		if b, ok := objB.(*cfgv1b1.GatewayClassConfig); ok {
 			if cmp.Equal(a.Status, b.Status, opts) {
 				return true
 			}
 		}
-	case *gwapiv1b1.GatewayClass:
-		if b, ok := objB.(*gwapiv1b1.GatewayClass); ok {
+	case *gwapiv1.GatewayClass:
+		if b, ok := objB.(*gwapiv1.GatewayClass); ok {
 			if cmp.Equal(a.Status, b.Status, opts) {
 				return true
 			}
 		}
-	case *gwapiv1b1.Gateway:
-		if b, ok := objB.(*gwapiv1b1.Gateway); ok {
+	case *gwapiv1.Gateway:
+		if b, ok := objB.(*gwapiv1.Gateway); ok {
 			if cmp.Equal(a.Status, b.Status, opts) {
 				return true
 			}
@@ -64,3 +67,32 @@ func IsEqual(objA, objB interface{}) bool {
 	}
 	return false
 }
+
+// Conditions returns the status conditions of obj, or nil if obj has no conditions.
+//
+// Supported objects are the objects supported by IsEqual.
+func Conditions(obj interface{}) []metav1.Condition {
+	switch o := toV1(obj).(type) {
+	case *gwapiv1.GatewayClass:
+		return o.Status.Conditions
+	case *gwapiv1.Gateway:
+		return o.Status.Conditions
+	}
+	return nil
+}
+
+// toV1 returns obj as a v1 object if it is a GatewayClass or Gateway of another
+// supported API version, or obj otherwise.
+func toV1(obj interface{}) interface{} {
+	o, ok := obj.(client.Object)
+	if !ok {
+		return obj
+	}
+	if gc, ok := gatewayapi.ToV1GatewayClass(o); ok {
+		return gc
+	}
+	if gw, ok := gatewayapi.ToV1Gateway(o); ok {
+		return gw
+	}
+	return obj
+}
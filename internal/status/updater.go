@@ -0,0 +1,253 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"solo.io/sample-gateway-manager/internal/events"
+	"solo.io/sample-gateway-manager/internal/metrics"
+	"solo.io/sample-gateway-manager/internal/tracing"
+)
+
+// Mutator applies a desired status to obj.
+type Mutator func(obj client.Object)
+
+// Update is a desired status of an object.
+type Update struct {
+	// Kind is the kind of the object, e.g. Gateway.
+	Kind           string
+	NamespacedName types.NamespacedName
+	// Resource is an empty object of the API version the object is read and written with.
+	Resource client.Object
+	// Mutate applies the desired status to the object.
+	Mutate Mutator
+}
+
+// updateKey identifies the object of an update.
+type updateKey struct {
+	kind   string
+	nsName types.NamespacedName
+}
+
+// pendingUpdate is the combined desired status of an object.
+type pendingUpdate struct {
+	resource client.Object
+	mutators []Mutator
+	links    []trace.Link
+}
+
+// Updater writes the desired statuses sent to it from a single goroutine. The updates
+// of an object sent before a write are combined, writes that don't change the status
+// are skipped, and writes that conflict are retried with the latest object. Writes
+// that fail otherwise are requeued with a rate-limited delay. Updates
+// are only written by the leader; until a replica is elected, only the latest update
+// of each object is kept, so standby replicas hold at most one update per object.
+type Updater struct {
+	Client client.Client
+	Log    logr.Logger
+	// Recorder records events for the condition transitions written.
+	Recorder *events.Recorder
+	// RateLimiter delays the requeue of the updates that failed to be written.
+	RateLimiter workqueue.RateLimiter
+
+	mu      sync.Mutex
+	pending map[updateKey]*pendingUpdate
+	// leading is true once the updater is started by the elected leader.
+	leading bool
+	// notify signals pending updates.
+	notify chan struct{}
+}
+
+// NewUpdater returns an Updater writing statuses with c.
+func NewUpdater(c client.Client, log logr.Logger, recorder *events.Recorder) *Updater {
+	return &Updater{
+		Client:      c,
+		Log:         log.WithName("status updater"),
+		Recorder:    recorder,
+		RateLimiter: workqueue.DefaultItemBasedRateLimiter(),
+		pending:     map[updateKey]*pendingUpdate{},
+		notify:      make(chan struct{}, 1),
+	}
+}
+
+// Send queues update without blocking. The span of ctx is linked to the span of the write.
+func (u *Updater) Send(ctx context.Context, update Update) {
+	key := updateKey{kind: update.Kind, nsName: update.NamespacedName}
+
+	u.mu.Lock()
+	pending, ok := u.pending[key]
+	if !ok {
+		pending = &pendingUpdate{}
+		u.pending[key] = pending
+	}
+	pending.resource = update.Resource
+	if !u.leading {
+		// The latest update of a standby replica replaces the previous ones, since the
+		// senders send the complete desired status of an object.
+		pending.mutators, pending.links = nil, nil
+	}
+	pending.mutators = append(pending.mutators, update.Mutate)
+	if sc := trace.SpanFromContext(ctx).SpanContext(); sc.IsValid() {
+		pending.links = append(pending.links, trace.Link{SpanContext: sc})
+	}
+	u.mu.Unlock()
+
+	u.signal()
+}
+
+// signal notifies the updater of pending updates without blocking.
+func (u *Updater) signal() {
+	select {
+	case u.notify <- struct{}{}:
+	default:
+	}
+}
+
+// requeue queues update again after the delay of the rate limiter. The updates of
+// the object sent in the meantime are applied after it.
+func (u *Updater) requeue(key updateKey, update *pendingUpdate) {
+	time.AfterFunc(u.RateLimiter.When(key), func() {
+		u.mu.Lock()
+		if pending, ok := u.pending[key]; ok {
+			pending.mutators = append(update.mutators, pending.mutators...)
+			pending.links = append(update.links, pending.links...)
+		} else {
+			u.pending[key] = update
+		}
+		u.mu.Unlock()
+
+		u.signal()
+	})
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable so only the leader
+// writes statuses.
+func (u *Updater) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable.
+func (u *Updater) Start(ctx context.Context) error {
+	u.Log.Info("started status updater")
+	u.mu.Lock()
+	u.leading = true
+	u.mu.Unlock()
+	// Write the updates kept while standby.
+	u.writePending(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-u.notify:
+			u.writePending(ctx)
+		}
+	}
+}
+
+// writePending writes the pending updates.
+func (u *Updater) writePending(ctx context.Context) {
+	u.mu.Lock()
+	batch := u.pending
+	u.pending = map[updateKey]*pendingUpdate{}
+	u.mu.Unlock()
+
+	for key, update := range batch {
+		u.write(ctx, key, update)
+	}
+}
+
+// write applies the mutators of update to the latest object and writes its status.
+func (u *Updater) write(ctx context.Context, key updateKey, update *pendingUpdate) {
+	ctx, span := tracing.Tracer().Start(ctx, "StatusUpdater.write", trace.WithLinks(update.links...),
+		trace.WithAttributes(tracing.AttrKind.String(key.kind),
+			tracing.AttrNamespace.String(key.nsName.Namespace), tracing.AttrName.String(key.nsName.Name)))
+	defer span.End()
+	log := u.Log.WithValues("kind", key.kind, "namespace", key.nsName.Namespace, "name", key.nsName.Name)
+
+	var current, desired client.Object
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		current = update.resource.DeepCopyObject().(client.Object)
+		if err := u.Client.Get(ctx, key.nsName, current); err != nil {
+			return err
+		}
+		desired = current.DeepCopyObject().(client.Object)
+		for _, mutate := range update.mutators {
+			mutate(desired)
+		}
+
+		// No status update needed.
+		if IsEqual(current, desired) {
+			desired = nil
+			return nil
+		}
+
+		err := u.Client.Status().Update(ctx, desired)
+		metrics.RecordStatusUpdate(key.kind, err)
+		return err
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		_ = tracing.RecordError(span, err)
+		log.Error(err, "failed to update status; requeuing")
+		if current.GetName() != "" {
+			u.Recorder.Warning(current, events.ReasonStatusUpdateFailed, "Failed to update the %s status: %v", key.kind, err)
+		}
+		u.requeue(key, update)
+		return
+	}
+	u.RateLimiter.Forget(key)
+	if err != nil {
+		return
+	}
+	if desired == nil {
+		return
+	}
+
+	log.V(1).Info("updated status")
+	u.recordTransitions(desired, Conditions(current), Conditions(desired))
+}
+
+// recordTransitions records an event for each condition of after that changed status
+// or reason from before.
+func (u *Updater) recordTransitions(obj client.Object, before, after []metav1.Condition) {
+	for _, cond := range after {
+		if prev := meta.FindStatusCondition(before, cond.Type); prev != nil &&
+			prev.Status == cond.Status && prev.Reason == cond.Reason {
+			continue
+		}
+		msg := fmt.Sprintf("%s: %s", cond.Type, cond.Message)
+		if cond.Status == metav1.ConditionTrue {
+			u.Recorder.Normal(obj, cond.Reason, "%s", msg)
+			continue
+		}
+		u.Recorder.Warning(obj, cond.Reason, "%s", msg)
+	}
+}
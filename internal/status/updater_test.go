@@ -0,0 +1,230 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"solo.io/sample-gateway-manager/internal/events"
+)
+
+// acceptGateway returns a mutator setting the Accepted condition of a gateway.
+func acceptGateway(status metav1.ConditionStatus, reason string) Mutator {
+	return func(obj client.Object) {
+		gw := obj.(*gwapiv1.Gateway)
+		gw.Status.Conditions = MergeConditions(gw.Status.Conditions, metav1.Condition{
+			Type: "Accepted", Status: status, Reason: reason, LastTransitionTime: metav1.Now(),
+		})
+	}
+}
+
+func newTestUpdater(t *testing.T, funcs interceptor.Funcs, objs ...client.Object) (*Updater, client.Client, *record.FakeRecorder) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := gwapiv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := gwapiv1b1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&gwapiv1.Gateway{}, &gwapiv1b1.Gateway{}).
+		WithInterceptorFuncs(funcs).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	return NewUpdater(c, logr.Discard(), events.NewRecorder(recorder, events.DefaultTTL)), c, recorder
+}
+
+func TestUpdaterWrite(t *testing.T) {
+	gw := &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+	var updates int
+	u, c, recorder := newTestUpdater(t, interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, sub string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			updates++
+			return c.SubResource(sub).Update(ctx, obj, opts...)
+		},
+	}, gw)
+	u.leading = true
+	key := client.ObjectKeyFromObject(gw)
+	ctx := context.Background()
+
+	// The updates of an object sent before a write are combined into one write.
+	u.Send(ctx, Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1.Gateway{},
+		Mutate: acceptGateway(metav1.ConditionFalse, "Pending")})
+	u.Send(ctx, Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1.Gateway{},
+		Mutate: acceptGateway(metav1.ConditionTrue, "Accepted")})
+	u.writePending(ctx)
+
+	got := new(gwapiv1.Gateway)
+	if err := c.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.Conditions) != 1 || got.Status.Conditions[0].Reason != "Accepted" {
+		t.Fatalf("expected the Accepted condition, found %+v", got.Status.Conditions)
+	}
+	if updates != 1 {
+		t.Errorf("expected 1 status update, found %d", updates)
+	}
+	if e := <-recorder.Events; e != "Normal Accepted Accepted: " {
+		t.Errorf("unexpected event %q", e)
+	}
+
+	// An update that doesn't change the status is skipped.
+	u.Send(ctx, Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1.Gateway{},
+		Mutate: acceptGateway(metav1.ConditionTrue, "Accepted")})
+	u.writePending(ctx)
+	if updates != 1 {
+		t.Errorf("expected the no-op update to be skipped, found %d updates", updates)
+	}
+}
+
+func TestUpdaterStandby(t *testing.T) {
+	u, _, _ := newTestUpdater(t, interceptor.Funcs{})
+	key := types.NamespacedName{Namespace: "default", Name: "test"}
+
+	// A standby replica keeps only the latest update of each object.
+	for i := 0; i < 3; i++ {
+		u.Send(context.Background(), Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1.Gateway{},
+			Mutate: acceptGateway(metav1.ConditionTrue, "Accepted")})
+	}
+	pending := u.pending[updateKey{kind: "Gateway", nsName: key}]
+	if pending == nil || len(pending.mutators) != 1 {
+		t.Errorf("expected 1 pending mutator, found %+v", pending)
+	}
+}
+
+func TestUpdaterRetriesConflicts(t *testing.T) {
+	gw := &gwapiv1b1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+	conflicts := 2
+	u, c, _ := newTestUpdater(t, interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, sub string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			if conflicts > 0 {
+				conflicts--
+				return apierrors.NewConflict(schema.GroupResource{Resource: "gateways"}, obj.GetName(), nil)
+			}
+			return c.SubResource(sub).Update(ctx, obj, opts...)
+		},
+	}, gw)
+	key := client.ObjectKeyFromObject(gw)
+	ctx := context.Background()
+
+	// The gateway is written as v1beta1.
+	u.Send(ctx, Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1b1.Gateway{},
+		Mutate: func(obj client.Object) {
+			acceptGateway(metav1.ConditionTrue, "Accepted")((*gwapiv1.Gateway)(obj.(*gwapiv1b1.Gateway)))
+		}})
+	u.writePending(ctx)
+
+	got := new(gwapiv1b1.Gateway)
+	if err := c.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.Conditions) != 1 {
+		t.Errorf("expected the status to be written after the conflicts, found %+v", got.Status.Conditions)
+	}
+}
+
+func TestUpdaterRequeuesFailures(t *testing.T) {
+	gw := &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+	failures := 1
+	u, c, _ := newTestUpdater(t, interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, sub string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			if failures > 0 {
+				failures--
+				return apierrors.NewInternalError(errors.New("etcd unavailable"))
+			}
+			return c.SubResource(sub).Update(ctx, obj, opts...)
+		},
+	}, gw)
+	u.RateLimiter = workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)
+	u.leading = true
+	key := client.ObjectKeyFromObject(gw)
+	ctx := context.Background()
+
+	u.Send(ctx, Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1.Gateway{},
+		Mutate: acceptGateway(metav1.ConditionTrue, "Accepted")})
+	<-u.notify
+	u.writePending(ctx)
+
+	// The failed update is queued again after the rate-limited delay.
+	select {
+	case <-u.notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the failed update to be requeued")
+	}
+	if n := u.RateLimiter.NumRequeues(updateKey{kind: "Gateway", nsName: key}); n != 1 {
+		t.Errorf("expected 1 requeue, found %d", n)
+	}
+	u.writePending(ctx)
+
+	got := new(gwapiv1.Gateway)
+	if err := c.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.Conditions) != 1 {
+		t.Errorf("expected the status to be written after the requeue, found %+v", got.Status.Conditions)
+	}
+	if n := u.RateLimiter.NumRequeues(updateKey{kind: "Gateway", nsName: key}); n != 0 {
+		t.Errorf("expected the requeues to be forgotten after the write, found %d", n)
+	}
+}
+
+func TestUpdaterStart(t *testing.T) {
+	gw := &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+	u, c, _ := newTestUpdater(t, interceptor.Funcs{}, gw)
+	key := client.ObjectKeyFromObject(gw)
+
+	// Updates sent before the updater starts, e.g. before the manager is elected
+	// leader, are written once it starts.
+	u.Send(context.Background(), Update{Kind: "Gateway", NamespacedName: key, Resource: &gwapiv1.Gateway{},
+		Mutate: acceptGateway(metav1.ConditionTrue, "Accepted")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = u.Start(ctx)
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		got := new(gwapiv1.Gateway)
+		if err := c.Get(ctx, key, got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Status.Conditions) == 1 {
+			return
+		}
+	}
+	t.Error("expected the status to be written")
+}
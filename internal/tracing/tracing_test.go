@@ -0,0 +1,99 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestSetup(t *testing.T) {
+	testCases := map[string]struct {
+		opts    Options
+		wantErr bool
+	}{
+		"disabled":               {opts: Options{}},
+		"disabled ignores ratio": {opts: Options{SampleRatio: 2}},
+		"enabled":                {opts: Options{Endpoint: "localhost:4317", Insecure: true, SampleRatio: 0.5}},
+		"invalid ratio":          {opts: Options{Endpoint: "localhost:4317", SampleRatio: 2}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tc.opts)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("unexpected shutdown error: %v", err)
+			}
+		})
+	}
+}
+
+func TestObjectAttributes(t *testing.T) {
+	gw := &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", Generation: 3}}
+
+	attrs := ObjectAttributes("Gateway", gw)
+	want := map[string]string{
+		string(AttrKind):       "Gateway",
+		string(AttrNamespace):  "default",
+		string(AttrName):       "test",
+		string(AttrGeneration): "3",
+	}
+	if len(attrs) != len(want) {
+		t.Fatalf("expected %d attributes, found %d", len(want), len(attrs))
+	}
+	for _, attr := range attrs {
+		if got := attr.Value.Emit(); got != want[string(attr.Key)] {
+			t.Errorf("expected attribute %s to be %q, found %q", attr.Key, want[string(attr.Key)], got)
+		}
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	_, span := tp.Tracer(TracerName).Start(context.Background(), "test")
+	failed := errors.New("failed")
+	if err := RecordError(span, failed); err != failed {
+		t.Errorf("expected error %v, found %v", failed, err)
+	}
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, found %d", len(spans))
+	}
+	if got := spans[0].Status().Code; got != codes.Error {
+		t.Errorf("expected span status %v, found %v", codes.Error, got)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package xds secures the xDS connections of the proxies with mutual TLS. The manager
+// manages a CA that issues a certificate to the proxies of each Gateway, and one to the
+// xDS server.
+package xds
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	// caValidity is the validity of the CA certificate.
+	caValidity = 10 * 365 * 24 * time.Hour
+	// caCommonName is the common name of the CA certificate.
+	caCommonName = "sample-gateway-manager-xds-ca"
+	// clockSkew backdates the certificates to tolerate the clock skew between the
+	// manager and the proxies.
+	clockSkew = 5 * time.Minute
+)
+
+// CA is the certificate authority of the xDS connections.
+type CA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// NewCA returns a new self-signed CA, valid from now.
+func NewCA(now time.Time) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the CA key: %w", err)
+	}
+	tmpl, err := template(caCommonName, now, caValidity)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the CA certificate: %w", err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return LoadCA(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM)
+}
+
+// LoadCA returns the CA of the PEM encoded certificate and private key.
+func LoadCA(certPEM, keyPEM []byte) (*CA, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA key pair: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate: %w", err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !cert.IsCA {
+		return nil, errors.New("invalid CA: expected an ECDSA CA certificate")
+	}
+	return &CA{cert: cert, key: key, certPEM: certPEM, keyPEM: keyPEM}, nil
+}
+
+// CertPEM returns the PEM encoded certificate of the CA.
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+// KeyPEM returns the PEM encoded private key of the CA.
+func (ca *CA) KeyPEM() []byte {
+	return ca.keyPEM
+}
+
+// Certificate is a certificate issued by the CA.
+type Certificate struct {
+	// CertPEM and KeyPEM are the PEM encoded certificate and private key.
+	CertPEM []byte
+	KeyPEM  []byte
+	// NotBefore and NotAfter bound the validity of the certificate.
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// RenewAt returns the time the certificate is renewed, once two thirds of its validity
+// elapsed.
+func (c *Certificate) RenewAt() time.Time {
+	return c.NotBefore.Add(c.NotAfter.Sub(c.NotBefore) * 2 / 3)
+}
+
+// Issue issues a certificate for client authentication only to commonName, valid for
+// validity from now, so a proxy can't present it as an xDS server.
+func (ca *CA) Issue(commonName string, validity time.Duration, now time.Time) (*Certificate, error) {
+	return ca.issue(commonName, nil, x509.ExtKeyUsageClientAuth, validity, now)
+}
+
+// IssueServer issues a certificate for server authentication only to the DNS name
+// dnsName, valid for validity from now, which the xDS server presents to the proxies.
+func (ca *CA) IssueServer(dnsName string, validity time.Duration, now time.Time) (*Certificate, error) {
+	return ca.issue(dnsName, []string{dnsName}, x509.ExtKeyUsageServerAuth, validity, now)
+}
+
+// issue issues a certificate for usage only to commonName and dnsNames, valid for
+// validity from now.
+func (ca *CA) issue(commonName string, dnsNames []string, usage x509.ExtKeyUsage, validity time.Duration, now time.Time) (*Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the key of %s: %w", commonName, err)
+	}
+	tmpl, err := template(commonName, now, validity)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.DNSNames = dnsNames
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the certificate of %s: %w", commonName, err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{
+		CertPEM:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:    keyPEM,
+		NotBefore: tmpl.NotBefore,
+		NotAfter:  tmpl.NotAfter,
+	}, nil
+}
+
+// Verify parses the PEM encoded certificate and private key, and returns the
+// certificate if it was issued by the CA to commonName for client authentication only
+// and is valid at now.
+func (ca *CA) Verify(certPEM, keyPEM []byte, commonName string, now time.Time) (*Certificate, error) {
+	cert, err := ca.verify(certPEM, keyPEM, x509.ExtKeyUsageClientAuth, now)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Subject.CommonName != commonName {
+		return nil, fmt.Errorf("certificate issued to %q, expected %q", cert.Subject.CommonName, commonName)
+	}
+	return &Certificate{CertPEM: certPEM, KeyPEM: keyPEM, NotBefore: cert.NotBefore, NotAfter: cert.NotAfter}, nil
+}
+
+// VerifyServer parses the PEM encoded certificate and private key, and returns the
+// certificate if it was issued by the CA to the DNS name dnsName for server
+// authentication only and is valid at now.
+func (ca *CA) VerifyServer(certPEM, keyPEM []byte, dnsName string, now time.Time) (*Certificate, error) {
+	cert, err := ca.verify(certPEM, keyPEM, x509.ExtKeyUsageServerAuth, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := cert.VerifyHostname(dnsName); err != nil {
+		return nil, err
+	}
+	return &Certificate{CertPEM: certPEM, KeyPEM: keyPEM, NotBefore: cert.NotBefore, NotAfter: cert.NotAfter}, nil
+}
+
+// verify parses the PEM encoded certificate and private key, and returns the
+// certificate if it was issued by the CA for usage only and is valid at now.
+func (ca *CA) verify(certPEM, keyPEM []byte, usage x509.ExtKeyUsage, now time.Time) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key pair: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	// Certificates valid for other usages, e.g. the proxy certificates of earlier
+	// versions also valid for server authentication, are replaced.
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != usage {
+		return nil, errors.New("certificate isn't restricted to its usage")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       ca.pool(),
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{usage},
+	}); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// pool returns a certificate pool of the CA.
+func (ca *CA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// template returns the template of a certificate issued to commonName, valid for
+// validity from now.
+func template(commonName string, now time.Time, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate a serial number: %w", err)
+	}
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-clockSkew),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+// encodeKey returns the PEM encoding of key.
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package xds
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ca, err := NewCA(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := NewCA(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := ca.Issue("default/gw-proxy", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[string]struct {
+		ca         *CA
+		commonName string
+		now        time.Time
+		wantErr    bool
+	}{
+		"valid":           {ca: ca, commonName: "default/gw-proxy", now: now.Add(30 * time.Minute)},
+		"other name":      {ca: ca, commonName: "default/other-proxy", now: now, wantErr: true},
+		"other ca":        {ca: other, commonName: "default/gw-proxy", now: now, wantErr: true},
+		"expired":         {ca: ca, commonName: "default/gw-proxy", now: now.Add(2 * time.Hour), wantErr: true},
+		"within the skew": {ca: ca, commonName: "default/gw-proxy", now: now.Add(-time.Minute)},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.ca.Verify(cert.CertPEM, cert.KeyPEM, tc.commonName, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, found %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadCA(t *testing.T) {
+	now := time.Now()
+	ca, err := NewCA(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := LoadCA(ca.CertPEM(), ca.KeyPEM())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := loaded.Issue("default/gw-proxy", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ca.Verify(cert.CertPEM, cert.KeyPEM, "default/gw-proxy", now); err != nil {
+		t.Errorf("expected a certificate issued by the loaded CA, found %v", err)
+	}
+
+	if _, err := LoadCA(cert.CertPEM, cert.KeyPEM); err == nil {
+		t.Error("expected an error loading a certificate that isn't a CA")
+	}
+}
+
+func TestRenewAt(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &Certificate{NotBefore: now, NotAfter: now.Add(24 * time.Hour)}
+	if want := now.Add(16 * time.Hour); !cert.RenewAt().Equal(want) {
+		t.Errorf("expected renewal at %s, found %s", want, cert.RenewAt())
+	}
+}
+
+func TestIssueClientOnly(t *testing.T) {
+	now := time.Now()
+	ca, err := NewCA(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issued, err := ca.Issue("default/gw-proxy", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	block, _ := pem.Decode(issued.CertPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A proxy certificate can't be presented as the certificate of an xDS server.
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: ca.pool(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err == nil {
+		t.Error("expected the certificate not to be valid for server authentication")
+	}
+	if len(cert.DNSNames) != 0 {
+		t.Errorf("expected no DNS names, found %v", cert.DNSNames)
+	}
+
+	// A certificate also valid for server authentication is replaced.
+	tmpl, err := template("default/gw-proxy", now, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &ca.key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dual := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if _, err := ca.Verify(dual, ca.KeyPEM(), "default/gw-proxy", now); err == nil {
+		t.Error("expected an error verifying a certificate valid for server authentication")
+	}
+}
+
+func TestVerifyServer(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ca, err := NewCA(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server, err := ca.IssueServer("xds.example.svc", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	proxy, err := ca.Issue("xds.example.svc", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[string]struct {
+		cert    *Certificate
+		dnsName string
+		now     time.Time
+		wantErr bool
+	}{
+		"valid":             {cert: server, dnsName: "xds.example.svc", now: now.Add(30 * time.Minute)},
+		"other name":        {cert: server, dnsName: "other.example.svc", now: now, wantErr: true},
+		"expired":           {cert: server, dnsName: "xds.example.svc", now: now.Add(2 * time.Hour), wantErr: true},
+		"proxy certificate": {cert: proxy, dnsName: "xds.example.svc", now: now, wantErr: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ca.VerifyServer(tc.cert.CertPEM, tc.cert.KeyPEM, tc.dnsName, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, found %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// A server certificate can't be presented by a proxy.
+	if _, err := ca.Verify(server.CertPEM, server.KeyPEM, "xds.example.svc", now); err == nil {
+		t.Error("expected an error verifying a server certificate for client authentication")
+	}
+}
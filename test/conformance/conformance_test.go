@@ -0,0 +1,202 @@
+//go:build conformance
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"flag"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gwapiv1a2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gwapiv1b1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	"sigs.k8s.io/gateway-api/conformance/tests"
+	"sigs.k8s.io/gateway-api/conformance/utils/flags"
+	"sigs.k8s.io/gateway-api/conformance/utils/suite"
+
+	cfgv1a1 "solo.io/sample-gateway-manager/api/v1alpha1"
+	cfgv1b1 "solo.io/sample-gateway-manager/api/v1beta1"
+	"solo.io/sample-gateway-manager/internal/model"
+)
+
+const (
+	// modeCluster runs the conformance tests against the cluster of the current
+	// kubeconfig context, e.g. a kind cluster with the manager deployed.
+	modeCluster = "cluster"
+	// modeEnvtest runs the control-plane-only conformance tests against a local
+	// API server with the manager running in-process.
+	modeEnvtest = "envtest"
+)
+
+// The upstream flags package registers the -gateway-class, -debug, -cleanup-base-resources,
+// -supported-features, -exempt-features and -all-features flags.
+var (
+	mode           = flag.String("mode", modeCluster, "Where to run the conformance tests, either \"cluster\" or \"envtest\"")
+	skipTests      = flag.String("skip-tests", "", "Comma-separated list of conformance tests to skip")
+	reportOutput   = flag.String("report-output", "", "Path of the conformance report; no report is written if unset")
+	controllerName = flag.String("controller-name", "sample.io/gateway-manager", "The controllerName of the manager under test")
+)
+
+// controlPlaneTests are the conformance tests that only check resource status and do
+// not send traffic through a Gateway, so they can run against envtest.
+var controlPlaneTests = sets.New(
+	"GatewayClassObservedGenerationBump",
+	"GatewayInvalidRouteKind",
+	"GatewayInvalidTLSConfiguration",
+	"GatewayObservedGenerationBump",
+	"GatewaySecretInvalidReferenceGrant",
+	"GatewaySecretMissingReferenceGrant",
+	"GatewaySecretReferenceGrantAllInNamespace",
+	"GatewaySecretReferenceGrantSpecific",
+)
+
+func TestConformance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1a2.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1b1.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
+	utilruntime.Must(cfgv1a1.AddToScheme(scheme))
+	utilruntime.Must(cfgv1b1.AddToScheme(scheme))
+
+	// The conformance profile defaults to the features the manager advertises on
+	// the accepted GatewayClass.
+	mgrCfg := &model.ManagerConfig{
+		Controllers: []model.ControllerConfig{{Name: *controllerName}},
+		// The proxies are provisioned but don't run against envtest.
+		ProxyImage:          "docker.io/envoyproxy/envoy:v1.28.0",
+		XDSAddress:          "xds.sample-gateway-manager.svc:18000",
+		CertificateValidity: time.Hour,
+	}
+
+	var cfg *rest.Config
+	switch *mode {
+	case modeCluster:
+		var err error
+		cfg, err = config.GetConfig()
+		if err != nil {
+			t.Fatalf("Error loading Kubernetes config: %v", err)
+		}
+	case modeEnvtest:
+		cfg = startEnvtest(t, scheme, *flags.GatewayClassName, mgrCfg)
+	default:
+		t.Fatalf("Unknown mode %q, must be %q or %q", *mode, modeCluster, modeEnvtest)
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatalf("Error initializing Kubernetes client: %v", err)
+	}
+
+	supportedFeatures := parseSupportedFeatures(*flags.SupportedFeatures)
+	if supportedFeatures.Len() == 0 {
+		for _, f := range model.SupportedFeatures() {
+			supportedFeatures.Insert(suite.SupportedFeature(f))
+		}
+	}
+	for feature := range parseSupportedFeatures(*flags.ExemptFeatures) {
+		supportedFeatures.Delete(feature)
+	}
+	skipped := sets.New(parseList(*skipTests)...)
+
+	t.Logf("Running conformance tests with %s GatewayClass\n mode: %s\n cleanup: %t\n debug: %t\n enable all features: %t\n supported features: [%v]\n exempt features: [%v]\n skip tests: [%v]",
+		*flags.GatewayClassName, *mode, *flags.CleanupBaseResources, *flags.ShowDebug, *flags.EnableAllSupportedFeatures,
+		*flags.SupportedFeatures, *flags.ExemptFeatures, *skipTests)
+
+	cSuite := suite.New(suite.Options{
+		Client:                     c,
+		GatewayClassName:           *flags.GatewayClassName,
+		Debug:                      *flags.ShowDebug,
+		CleanupBaseResources:       *flags.CleanupBaseResources,
+		SupportedFeatures:          supportedFeatures,
+		EnableAllSupportedFeatures: *flags.EnableAllSupportedFeatures,
+	})
+
+	conformanceTests := tests.ConformanceTests
+	if *mode == modeEnvtest {
+		setupEnvtestSuite(t, cSuite)
+		conformanceTests = nil
+		for _, test := range tests.ConformanceTests {
+			if controlPlaneTests.Has(test.ShortName) {
+				conformanceTests = append(conformanceTests, test)
+			}
+		}
+	} else {
+		cSuite.Setup(t)
+	}
+
+	report := newReport(*mode, cSuite)
+	var mu sync.Mutex
+
+	// Parallel conformance tests only complete when their parent test does, so the
+	// tests are grouped to collect all results before writing the report.
+	t.Run("tests", func(t *testing.T) {
+		for _, test := range conformanceTests {
+			test := test
+			t.Run(test.ShortName, func(t *testing.T) {
+				t.Cleanup(func() {
+					mu.Lock()
+					defer mu.Unlock()
+					report.addResult(test.ShortName, t.Failed(), t.Skipped())
+				})
+				if skipped.Has(test.ShortName) {
+					t.Skipf("Skipping %s: listed in -skip-tests", test.ShortName)
+				}
+				test.Run(t, cSuite)
+			})
+		}
+	})
+
+	if *reportOutput != "" {
+		if err := report.write(*reportOutput); err != nil {
+			t.Fatalf("Error writing conformance report: %v", err)
+		}
+		t.Logf("Conformance report written to %s", *reportOutput)
+	}
+}
+
+// parseSupportedFeatures parses a comma-separated list of features.
+func parseSupportedFeatures(f string) sets.Set[suite.SupportedFeature] {
+	res := sets.Set[suite.SupportedFeature]{}
+	for _, value := range parseList(f) {
+		res.Insert(suite.SupportedFeature(value))
+	}
+	return res
+}
+
+// parseList splits a comma-separated flag value, ignoring empty items.
+func parseList(s string) []string {
+	var res []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			res = append(res, value)
+		}
+	}
+	return res
+}
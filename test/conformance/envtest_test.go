@@ -0,0 +1,179 @@
+//go:build conformance
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/conformance/utils/kubernetes"
+	"sigs.k8s.io/gateway-api/conformance/utils/suite"
+
+	"solo.io/sample-gateway-manager/internal/events"
+	kube "solo.io/sample-gateway-manager/internal/kubernetes"
+	"solo.io/sample-gateway-manager/internal/model"
+	"solo.io/sample-gateway-manager/internal/status"
+	"solo.io/sample-gateway-manager/internal/xds"
+)
+
+// startEnvtest starts a local API server with the manager running in-process and
+// creates the named GatewayClass for the manager to accept.
+func startEnvtest(t *testing.T, scheme *runtime.Scheme, gatewayClassName string, mgrCfg *model.ManagerConfig) *rest.Config {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set; run with \"make conformance-envtest\"")
+	}
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "config", "crd", "bases"),
+			filepath.Join("..", "..", "test", "crds", "gateway-api"),
+		},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatalf("Error starting envtest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := testEnv.Stop(); err != nil {
+			t.Errorf("Error stopping envtest: %v", err)
+		}
+	})
+
+	logger := zap.New(zap.UseDevMode(true))
+	ctrl.SetLogger(logger)
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatalf("Error creating manager: %v", err)
+	}
+
+	procChan := make(chan event.GenericEvent)
+	store := kube.NewObjectStore()
+
+	recorder := events.NewRecorder(mgr.GetEventRecorderFor("sample-gateway-manager"), events.DefaultTTL)
+	statusUpdater := status.NewUpdater(mgr.GetClient(), logger, recorder)
+	if err := mgr.Add(statusUpdater); err != nil {
+		t.Fatalf("Error adding status updater: %v", err)
+	}
+
+	if err := (&kube.GatewayClassReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		Log:           logger,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatalf("Error creating GatewayClass controller: %v", err)
+	}
+	if err := (&kube.GatewayReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		Log:           logger,
+		ObjectStore:   store,
+		ProcessorChan: procChan,
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatalf("Error creating Gateway controller: %v", err)
+	}
+	ca, err := xds.NewCA(time.Now())
+	if err != nil {
+		t.Fatalf("Error creating xDS CA: %v", err)
+	}
+	if err := (&kube.Processor{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgrCfg,
+		Log:           logger,
+		ObjectStore:   store,
+		UpdateChan:    procChan,
+		StatusUpdater: statusUpdater,
+		CA:            ca,
+		APIReader:     mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatalf("Error creating Processor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := mgr.Start(ctx); err != nil {
+			t.Errorf("Error running manager: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	gc := &gwapiv1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: gatewayClassName},
+		Spec: gwapiv1.GatewayClassSpec{
+			ControllerName: gwapiv1.GatewayController(mgrCfg.Controllers[0].Name),
+		},
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatalf("Error initializing Kubernetes client: %v", err)
+	}
+	if err := c.Create(context.Background(), gc); err != nil {
+		t.Fatalf("Error creating GatewayClass %s: %v", gatewayClassName, err)
+	}
+
+	return cfg
+}
+
+// setupEnvtestSuite installs the base conformance resources like suite.Setup does,
+// but without waiting for Pods to become ready since envtest does not run any.
+func setupEnvtestSuite(t *testing.T, s *suite.ConformanceTestSuite) {
+	t.Logf("Test Setup: Ensuring GatewayClass has been accepted")
+	s.ControllerName = kubernetes.GWCMustHaveAcceptedConditionTrue(t, s.Client, s.TimeoutConfig, s.GatewayClassName)
+
+	s.Applier.GatewayClass = s.GatewayClassName
+	s.Applier.ControllerName = s.ControllerName
+
+	t.Logf("Test Setup: Applying base manifests")
+	s.Applier.MustApplyWithCleanup(t, s.Client, s.TimeoutConfig, s.BaseManifests, s.Cleanup)
+
+	t.Logf("Test Setup: Applying programmatic resources")
+	secrets := []client.Object{
+		kubernetes.MustCreateSelfSignedCertSecret(t, "gateway-conformance-web-backend", "certificate", []string{"*"}),
+		kubernetes.MustCreateSelfSignedCertSecret(t, "gateway-conformance-infra", "tls-validity-checks-certificate", []string{"*"}),
+		kubernetes.MustCreateSelfSignedCertSecret(t, "gateway-conformance-infra", "tls-passthrough-checks-certificate", []string{"abc.example.com"}),
+	}
+	s.Applier.MustApplyObjectsWithCleanup(t, s.Client, s.TimeoutConfig, secrets, s.Cleanup)
+}
@@ -0,0 +1,106 @@
+//go:build conformance
+
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"os"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"sigs.k8s.io/gateway-api/conformance/utils/suite"
+	"sigs.k8s.io/yaml"
+)
+
+// Report summarizes the result of a conformance run.
+type Report struct {
+	// Date is the RFC 3339 time the conformance run started.
+	Date string `json:"date"`
+	// Project is the name of the implementation under test.
+	Project string `json:"project"`
+	// GatewayAPIVersion is the version of the conformance suite that was run.
+	GatewayAPIVersion string `json:"gatewayAPIVersion"`
+	// Mode is where the conformance tests ran, either "cluster" or "envtest".
+	Mode string `json:"mode"`
+	// GatewayClassName is the name of the GatewayClass under test.
+	GatewayClassName string `json:"gatewayClassName"`
+	// SupportedFeatures are the features the conformance tests ran with.
+	SupportedFeatures []string `json:"supportedFeatures"`
+	// Passed lists the names of the passed conformance tests.
+	Passed []string `json:"passed"`
+	// Failed lists the names of the failed conformance tests.
+	Failed []string `json:"failed"`
+	// Skipped lists the names of the skipped conformance tests.
+	Skipped []string `json:"skipped"`
+}
+
+func newReport(mode string, s *suite.ConformanceTestSuite) *Report {
+	var features []string
+	for feature := range s.SupportedFeatures {
+		features = append(features, string(feature))
+	}
+	sort.Strings(features)
+
+	return &Report{
+		Date:              time.Now().Format(time.RFC3339),
+		Project:           "sample-gateway-manager",
+		GatewayAPIVersion: gatewayAPIVersion(),
+		Mode:              mode,
+		GatewayClassName:  s.GatewayClassName,
+		SupportedFeatures: features,
+	}
+}
+
+func (r *Report) addResult(name string, failed, skipped bool) {
+	switch {
+	case skipped:
+		r.Skipped = append(r.Skipped, name)
+	case failed:
+		r.Failed = append(r.Failed, name)
+	default:
+		r.Passed = append(r.Passed, name)
+	}
+}
+
+// write writes the report as YAML to path.
+func (r *Report) write(path string) error {
+	for _, names := range [][]string{r.Passed, r.Failed, r.Skipped} {
+		sort.Strings(names)
+	}
+
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
+
+// gatewayAPIVersion returns the version of the Gateway API module the conformance
+// tests were built with.
+func gatewayAPIVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "sigs.k8s.io/gateway-api" {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}